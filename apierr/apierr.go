@@ -0,0 +1,131 @@
+// Package apierr описывает единый формат ответа с ошибкой:
+//
+//	{"error": "Не указан заголовок задачи", "code": "validation_failed", "fields": {"title": "Не указан заголовок задачи"}}
+//
+// Поле error — сообщение для человека, code — стабильный код, по которому клиенты
+// различают ошибки, fields — ошибки в отдельных полях запроса
+package apierr
+
+import (
+	"encoding/json"
+	"net/http"
+	"todo-app/i18n"
+)
+
+// Коды ошибок. Это часть API: коды не переименовываются и не удаляются
+const (
+	CodeInvalidJSON            = "invalid_json"              // тело запроса не разбирается как JSON
+	CodeInvalidID              = "invalid_id"                // идентификатор не указан или некорректен
+	CodeValidation             = "validation_failed"         // некорректное значение поля или параметра
+	CodeUnauthorized           = "unauthorized"              // нет действующего токена или сессии
+	CodeInvalidCredentials     = "invalid_credentials"       // неверный логин или пароль
+	CodeTwoFactorRequired      = "two_factor_required"       // для входа нужен код второго фактора
+	CodeInvalidTwoFactorCode   = "invalid_two_factor_code"   // неверный код второго фактора
+	CodeTwoFactorSetupRequired = "two_factor_setup_required" // нужно включить двухфакторную аутентификацию
+	CodeForbidden              = "forbidden"                 // недостаточно прав
+	CodeInsufficientScope      = "insufficient_scope"        // недостаточно прав персонального токена
+	CodeCrossSite              = "cross_site_request"        // изменяющий запрос пришёл с чужого сайта
+	CodeNotFound               = "not_found"                 // объект не найден
+	CodeMethodNotAllowed       = "method_not_allowed"        // метод не поддерживается
+	CodeConflict               = "conflict"                  // конфликт с текущим состоянием
+	CodeTaskBlocked            = "task_blocked"              // задача заблокирована невыполненными задачами
+	CodePreconditionFailed     = "precondition_failed"       // объект изменён после того, как клиент его получил
+	CodePreconditionRequired   = "precondition_required"     // не указана версия изменяемого объекта
+	CodeIdempotencyKeyInUse    = "idempotency_key_in_use"    // запрос с этим ключом идемпотентности ещё выполняется
+	CodeIdempotencyKeyReused   = "idempotency_key_reused"    // ключ идемпотентности использован для другого запроса
+	CodeBatchAborted           = "batch_aborted"             // операция пакета отменена из-за ошибки другой операции
+	CodePayloadTooLarge        = "payload_too_large"         // слишком большой файл
+	CodeUnsupportedMediaType   = "unsupported_media_type"    // неподдерживаемый тип тела запроса
+	CodeTooManyRequests        = "too_many_requests"         // слишком много попыток
+	CodeInternal               = "internal_error"            // внутренняя ошибка сервера
+	CodeBadGateway             = "bad_gateway"               // ошибка внешнего сервиса
+)
+
+// Error — тело ответа с ошибкой
+type Error struct {
+	Message string            `json:"error"`
+	Code    string            `json:"code"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// CodeForStatus возвращает код ошибки по умолчанию для HTTP-статуса
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeValidation
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusPreconditionFailed:
+		return CodePreconditionFailed
+	case http.StatusPreconditionRequired:
+		return CodePreconditionRequired
+	case http.StatusFailedDependency:
+		return CodeBatchAborted
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMediaType
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case http.StatusBadGateway:
+		return CodeBadGateway
+	default:
+		return CodeInternal
+	}
+}
+
+// Respond отправляет ошибку с заданным статусом
+func Respond(w http.ResponseWriter, r *http.Request, status int, e Error) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("Content-Language", i18n.Language(r))
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(e)
+}
+
+// Write отправляет ошибку с кодом по умолчанию для статуса.
+// Сообщение переводится на язык запроса, уже переведённое выводится как есть
+func Write(w http.ResponseWriter, r *http.Request, status int, message string) {
+	WriteCode(w, r, status, CodeForStatus(status), message)
+}
+
+// WriteCode отправляет ошибку с заданным кодом
+func WriteCode(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	Respond(w, r, status, NewCode(r, code, message))
+}
+
+// WriteError отправляет ошибку с текстом err и кодом по умолчанию для статуса
+func WriteError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	Write(w, r, status, i18n.Error(r, err))
+}
+
+// WriteField отправляет ошибку проверки одного поля запроса
+func WriteField(w http.ResponseWriter, r *http.Request, field, message string) {
+	Respond(w, r, http.StatusBadRequest, NewField(r, field, message))
+}
+
+// New возвращает ошибку с кодом по умолчанию для статуса, не отправляя её.
+// Нужна, когда ошибка — часть ответа, например результат операции пакета
+func New(r *http.Request, status int, message string) Error {
+	return NewCode(r, CodeForStatus(status), message)
+}
+
+// NewCode возвращает ошибку с заданным кодом, не отправляя её
+func NewCode(r *http.Request, code, message string) Error {
+	return Error{Message: i18n.T(r, message), Code: code}
+}
+
+// NewField возвращает ошибку проверки одного поля, не отправляя её
+func NewField(r *http.Request, field, message string) Error {
+	message = i18n.T(r, message)
+	return Error{Message: message, Code: CodeValidation, Fields: map[string]string{field: message}}
+}
@@ -1,115 +1,252 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"log"
+	"math"
 	"net/http"
 	"os"
-	"time"
+	"slices"
+	"strconv"
+	"strings"
+	"todo-app/apierr"
+	"todo-app/db"
+	"todo-app/i18n"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
 // Claims структура для JWT токена
 type Claims struct {
-	Authorized bool `json:"authorized"`
+	Authorized bool   `json:"authorized"`
+	UserID     int64  `json:"uid"`
+	SessionID  string `json:"sid"`
 	jwt.StandardClaims
 }
 
-// SigninHandler обрабатывает запросы на аутентификацию
+type (
+	contextKey        struct{}
+	sessionContextKey struct{}
+	scopesContextKey  struct{}
+)
+
+// SigninHandler обрабатывает запросы на аутентификацию.
+// Если логин не указан, вход выполняется под встроенным администратором
 func SigninHandler(w http.ResponseWriter, r *http.Request) {
 	var credentials struct {
-		Password string `json:"password"`
+		Login        string `json:"login"`
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
-		http.Error(w, `{"error": "Ошибка десериализации JSON"}`, http.StatusBadRequest)
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
 		return
 	}
-
-	expectedPassword, passwordExists := os.LookupEnv("TODO_PASSWORD")
-	if passwordExists && credentials.Password != expectedPassword {
-		http.Error(w, `{"error": "Неверный пароль"}`, http.StatusUnauthorized)
-		return
+	if credentials.Login == "" {
+		credentials.Login = adminLogin()
 	}
 
-	// Проверка существующего токена в куках
-	cookie, err := r.Cookie("token")
-	if err == nil {
-		tokenStr := cookie.Value
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(expectedPassword), nil
-		})
-		if err == nil && token.Valid && claims.ExpiresAt > time.Now().Unix() {
-			response := map[string]string{"token": tokenStr}
-			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-			json.NewEncoder(w).Encode(response)
-			return
-		}
+	// Пока логин или адрес заблокированы после неудачных попыток, пароль не проверяется
+	ip := clientIP(r)
+	if wait := signins.retryAfter(credentials.Login, ip); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		apierr.Write(w, r, http.StatusTooManyRequests, "Слишком много попыток входа, попробуйте позже")
+		return
 	}
 
-	// Генерация нового токена
-	expirationTime := time.Now().Add(8 * time.Hour)
-	claims := &Claims{
-		Authorized: true,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-		},
+	user, err := db.GetUserByLogin(credentials.Login)
+	if err != nil && !errors.Is(err, db.ErrUserNotFound) {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении пользователя")
+		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(expectedPassword))
-	if err != nil {
-		http.Error(w, `{"error": "Ошибка при создании токена"}`, http.StatusInternalServerError)
+	// Несуществующий логин считается такой же неудачной попыткой, как неверный пароль
+	_, passwordExists := os.LookupEnv("TODO_PASSWORD")
+	if err != nil || (passwordExists && !CheckPassword(user.PasswordHash, credentials.Password)) {
+		failures := signins.fail(credentials.Login, ip)
+		log.Printf("Failed sign-in for %q from %s (%d in a row)", credentials.Login, ip, failures)
+		apierr.WriteCode(w, r, http.StatusUnauthorized, apierr.CodeInvalidCredentials, "Неверный логин или пароль")
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "token",
-		Value:    tokenString,
-		Path:     "/",
-		Expires:  expirationTime,
-		HttpOnly: true,
-	})
+	// После пароля проверяется второй фактор, если пользователь его подключил
+	if passwordExists && user.TOTPEnabled {
+		if credentials.Code == "" && credentials.RecoveryCode == "" {
+			apierr.WriteCode(w, r, http.StatusUnauthorized, apierr.CodeTwoFactorRequired, "Требуется код двухфакторной аутентификации")
+			return
+		}
+		ok, err := checkSecondFactor(user, credentials.Code, credentials.RecoveryCode)
+		if err != nil {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при проверке кода")
+			return
+		}
+		if !ok {
+			failures := signins.fail(credentials.Login, ip)
+			log.Printf("Failed second factor for %q from %s (%d in a row)", credentials.Login, ip, failures)
+			apierr.WriteCode(w, r, http.StatusUnauthorized, apierr.CodeInvalidTwoFactorCode, "Неверный код двухфакторной аутентификации")
+			return
+		}
+	}
+	signins.succeed(credentials.Login)
 
-	response := map[string]string{"token": tokenString}
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	json.NewEncoder(w).Encode(response)
+	// Каждый вход открывает новую сессию
+	startSession(w, r, userIDOf(user))
 }
 
-// AuthMiddleware проверяет JWT токен в заголовке запроса
+// AuthMiddleware проверяет JWT токен или персональный токен доступа
+// и сохраняет пользователя из токена в контексте запроса
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, passwordExists := os.LookupEnv("TODO_PASSWORD")
 
-		// Если пароль не установлен, пропускаем запрос без проверки
+		// Если пароль не установлен, запрос выполняется от имени администратора без проверки
 		if !passwordExists {
-			next.ServeHTTP(w, r)
+			user, err := db.GetUserByLogin(adminLogin())
+			if err != nil {
+				apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении пользователя")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withUser(r.Context(), user)))
 			return
 		}
 
-		tokenCookie, err := r.Cookie("token")
-		if err != nil {
-			http.Error(w, "Authentication required", http.StatusUnauthorized)
+		// Скрипты передают токен в заголовке Authorization, браузер — в куке
+		tokenString := bearerToken(r)
+		if tokenString == "" {
+			if tokenCookie, err := r.Cookie("token"); err == nil {
+				tokenString = tokenCookie.Value
+			}
+		}
+		if tokenString == "" {
+			apierr.Write(w, r, http.StatusUnauthorized, "Требуется аутентификация")
+			return
+		}
+		if strings.HasPrefix(tokenString, apiTokenPrefix) {
+			serveAPIToken(w, r, next, tokenString)
 			return
 		}
-
-		tokenString := tokenCookie.Value
-		expectedPassword := os.Getenv("TODO_PASSWORD")
 
 		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		token, err := parseToken(tokenString, claims)
+
+		// Токены, выданные до появления учётных записей и сессий, не содержат пользователя и сессии
+		if err != nil || !token.Valid || claims.UserID == 0 || claims.SessionID == "" {
+			apierr.Write(w, r, http.StatusUnauthorized, "Требуется аутентификация")
+			return
+		}
+
+		// Токен действует, пока не отозвана сессия, в которой он выдан
+		session, err := db.GetSession(claims.SessionID)
+		if errors.Is(err, db.ErrSessionNotFound) || (err == nil && session.UserID != claims.UserID) {
+			apierr.Write(w, r, http.StatusUnauthorized, "Требуется аутентификация")
+			return
+		} else if err != nil {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении сессии")
+			return
+		}
+		if err := db.TouchSession(session.ID, clientIP(r)); err != nil {
+			log.Printf("Failed to update session %s: %v", session.ID, err)
+		}
+
+		user, err := db.GetUserByID(claims.UserID)
+		if errors.Is(err, db.ErrUserNotFound) {
+			apierr.Write(w, r, http.StatusUnauthorized, "Требуется аутентификация")
+			return
+		} else if err != nil {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении пользователя")
+			return
+		}
+
+		r = r.WithContext(withUser(r.Context(), user))
+		if !checkSecondFactorRequirement(w, r, user) {
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), sessionContextKey{}, session.ID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Отклоняем запрос, если администратор требует двухфакторную аутентификацию,
+// а пользователь её ещё не подключил
+func checkSecondFactorRequirement(w http.ResponseWriter, r *http.Request, user db.User) bool {
+	allowed, err := secondFactorAllowed(r, user)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении настроек")
+		return false
+	}
+	if !allowed {
+		apierr.WriteCode(w, r, http.StatusForbidden, apierr.CodeTwoFactorSetupRequired, "Необходимо включить двухфакторную аутентификацию")
+		return false
+	}
+	return true
+}
+
+// RequireRole пропускает только запросы пользователей с одной из ролей.
+// Используется после AuthMiddleware
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(roles, CurrentUser(r).Role) {
+				apierr.Write(w, r, http.StatusForbidden, "Недостаточно прав")
+				return
 			}
-			return []byte(expectedPassword), nil
+			next.ServeHTTP(w, r)
 		})
+	}
+}
 
-		if err != nil || !token.Valid {
-			http.Error(w, "Authentication required", http.StatusUnauthorized)
+// WriteAccessMiddleware пропускает запросы на чтение от всех ролей, а изменяющие
+// запросы — только от участников и администраторов. Используется после AuthMiddleware
+func WriteAccessMiddleware(next http.Handler) http.Handler {
+	writers := RequireRole(db.RoleAdmin, db.RoleMember)(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
 			return
 		}
+		writers.ServeHTTP(w, r)
+	})
+}
 
+// AdminMiddleware пропускает только запросы администраторов.
+// Персональному токену для этого нужна область admin. Используется после AuthMiddleware
+func AdminMiddleware(next http.Handler) http.Handler {
+	return RequireRole(db.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hasScope(r, ScopeAdmin) {
+			apierr.WriteCode(w, r, http.StatusForbidden, apierr.CodeInsufficientScope, "Недостаточно прав токена")
+			return
+		}
 		next.ServeHTTP(w, r)
-	})
+	}))
+}
+
+// Запоминаем в контексте пользователя, от имени которого выполняется запрос,
+// и выбранный им язык сообщений
+func withUser(ctx context.Context, user db.User) context.Context {
+	ctx = context.WithValue(ctx, contextKey{}, user)
+	if user.Language != "" {
+		ctx = i18n.WithLanguage(ctx, user.Language)
+	}
+	return ctx
+}
+
+// CurrentUser возвращает пользователя, от имени которого выполняется запрос
+func CurrentUser(r *http.Request) db.User {
+	user, _ := r.Context().Value(contextKey{}).(db.User)
+	return user
+}
+
+// UserID возвращает идентификатор пользователя, от имени которого выполняется запрос
+func UserID(r *http.Request) int64 {
+	return userIDOf(CurrentUser(r))
+}
+
+// Числовой идентификатор пользователя
+func userIDOf(user db.User) int64 {
+	id, _ := strconv.ParseInt(user.ID, 10, 64)
+	return id
 }
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"todo-app/apierr"
+)
+
+// Куки передаются только по HTTPS, если сервер принимает TLS сам
+// или работает за HTTPS-прокси (TODO_COOKIE_SECURE=true)
+func secureCookies(r *http.Request) bool {
+	return r.TLS != nil || os.Getenv("TODO_COOKIE_SECURE") == "true"
+}
+
+// Проверяем, что адрес страницы, с которой отправлен запрос, принадлежит этому сайту
+// или входит в список TODO_ALLOWED_ORIGINS
+func sameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range strings.Split(os.Getenv("TODO_ALLOWED_ORIGINS"), ",") {
+		allowed = strings.TrimSuffix(strings.TrimSpace(allowed), "/")
+		if allowed != "" && strings.EqualFold(allowed, u.Scheme+"://"+u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// CSRFMiddleware отклоняет изменяющие запросы, отправленные браузером с чужого сайта.
+// Браузер указывает источник запроса в заголовке Origin или Referer; запросы без них
+// (curl, скрипты) и запросы с заголовком Authorization, который чужой сайт подставить
+// не может, пропускаются
+func CSRFMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		source := r.Header.Get("Origin")
+		if source == "" {
+			source = r.Header.Get("Referer")
+		}
+		if source != "" && !sameOrigin(r, source) {
+			apierr.WriteCode(w, r, http.StatusForbidden, apierr.CodeCrossSite, "Запрос с чужого сайта отклонён")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"todo-app/apierr"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Время жизни токена; выведенный из обращения ключ хранится столько же,
+// чтобы подписанные им токены оставались действительными до истечения
+const tokenLifetime = 8 * time.Hour
+
+// Минимальная длина секрета ключа подписи в байтах
+const minKeySize = 32
+
+// Ключ подписи JWT. Токен ссылается на ключ через заголовок kid
+type signingKey struct {
+	ID        string `json:"kid"`
+	Secret    string `json:"secret"` // в шестнадцатеричном виде
+	CreatedAt string `json:"created_at"`
+	RetiredAt string `json:"retired_at,omitempty"`
+
+	secret []byte
+}
+
+// Набор ключей подписи: новым токенам ставится подпись текущим ключом,
+// а проверяются токены любым из ключей набора
+type keySet struct {
+	Current string       `json:"current"`
+	Keys    []signingKey `json:"keys"`
+
+	mu   sync.RWMutex
+	path string
+}
+
+var keys *keySet
+
+var errNoSigningKey = errors.New("ключ подписи не настроен")
+
+// Определяем путь к файлу ключей из переменной окружения TODO_JWT_KEYS_FILE
+func keysFile() string {
+	if path := os.Getenv("TODO_JWT_KEYS_FILE"); path != "" {
+		return path
+	}
+	return "jwt_keys.json"
+}
+
+// InitKeys загружает ключи подписи из файла, а если файла нет — создаёт его с новым ключом.
+// Повреждённый файл или слабый ключ останавливают запуск приложения
+func InitKeys() {
+	set, err := loadKeys(keysFile())
+	if errors.Is(err, os.ErrNotExist) {
+		set = &keySet{path: keysFile()}
+		if err = set.rotate(); err == nil {
+			log.Printf("Created JWT signing keys file: %s", set.path)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+	keys = set
+}
+
+// Загружаем и проверяем набор ключей
+func loadKeys(path string) (*keySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &keySet{path: path}
+	if err := json.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	seen := map[string]bool{}
+	for i := range set.Keys {
+		key := &set.Keys[i]
+		if key.ID == "" || seen[key.ID] {
+			return nil, fmt.Errorf("%s: missing or duplicate kid %q", path, key.ID)
+		}
+		seen[key.ID] = true
+
+		key.secret, err = hex.DecodeString(key.Secret)
+		if err != nil || len(key.secret) < minKeySize {
+			return nil, fmt.Errorf("%s: key %q must be at least %d random bytes in hex", path, key.ID, minKeySize)
+		}
+	}
+	if !seen[set.Current] {
+		return nil, fmt.Errorf("%s: current key %q not found", path, set.Current)
+	}
+	return set, nil
+}
+
+// Создаём новый текущий ключ, выводим прежний из обращения
+// и удаляем ключи, подписанные которыми токены уже истекли
+func (s *keySet) rotate() error {
+	secret := make([]byte, minKeySize)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	kept := make([]signingKey, 0, len(s.Keys)+1)
+	for _, key := range s.Keys {
+		if key.ID == s.Current {
+			key.RetiredAt = now.Format(time.RFC3339)
+		}
+		if retired, err := time.Parse(time.RFC3339, key.RetiredAt); err == nil && now.Sub(retired) > tokenLifetime {
+			continue
+		}
+		kept = append(kept, key)
+	}
+
+	key := signingKey{
+		ID:        hex.EncodeToString(id),
+		Secret:    hex.EncodeToString(secret),
+		CreatedAt: now.Format(time.RFC3339),
+		secret:    secret,
+	}
+	kept = append(kept, key)
+
+	// Набор в памяти меняется только после успешной записи файла
+	if err := saveKeys(s.path, key.ID, kept); err != nil {
+		return err
+	}
+	s.Keys = kept
+	s.Current = key.ID
+	return nil
+}
+
+// Сохраняем набор ключей: файл заменяется целиком и доступен только владельцу
+func saveKeys(path, current string, keys []signingKey) error {
+	data, err := json.MarshalIndent(struct {
+		Current string       `json:"current"`
+		Keys    []signingKey `json:"keys"`
+	}{current, keys}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".jwt_keys-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// RotateKeys создаёт новый ключ подписи. Токены, подписанные прежними ключами,
+// остаются действительными до истечения срока
+func RotateKeys() (string, error) {
+	if keys == nil {
+		return "", errNoSigningKey
+	}
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+	if err := keys.rotate(); err != nil {
+		return "", err
+	}
+	return keys.Current, nil
+}
+
+// Подписываем токен текущим ключом
+func signToken(claims jwt.Claims) (string, error) {
+	if keys == nil {
+		return "", errNoSigningKey
+	}
+	keys.mu.RLock()
+	defer keys.mu.RUnlock()
+
+	for _, key := range keys.Keys {
+		if key.ID == keys.Current {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+			token.Header["kid"] = key.ID
+			return token.SignedString(key.secret)
+		}
+	}
+	return "", errNoSigningKey
+}
+
+// Проверяем подпись токена ключом из его заголовка kid.
+// Токены без kid или с неизвестным ключом отклоняются
+func parseToken(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		if keys == nil {
+			return nil, errNoSigningKey
+		}
+		kid, _ := token.Header["kid"].(string)
+
+		keys.mu.RLock()
+		defer keys.mu.RUnlock()
+		for _, key := range keys.Keys {
+			if key.ID == kid {
+				return key.secret, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	})
+}
+
+// RotateKeysHandler создаёт новый ключ подписи по запросу администратора
+func RotateKeysHandler(w http.ResponseWriter, r *http.Request) {
+	kid, err := RotateKeys()
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при создании ключа подписи")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{"kid": kid})
+}
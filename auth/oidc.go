@@ -0,0 +1,670 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"todo-app/apierr"
+	"todo-app/db"
+	"unicode/utf8"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Сколько ждать возврата пользователя от провайдера после начала входа
+const oidcLoginTimeout = 10 * time.Minute
+
+// Сколько ждать кода второго фактора после возврата от провайдера
+const oidcChallengeTimeout = 5 * time.Minute
+
+// Не чаще этого перезагружаем ключи провайдера, встретив неизвестный kid
+const oidcKeysRefreshInterval = time.Minute
+
+// Не чаще этого перезагружаем ключи провайдера, если подпись не совпала с ключом
+// известного kid: провайдер мог сменить ключ, оставив прежний kid
+const oidcKeysReloadInterval = 5 * time.Second
+
+var oidcClient = &http.Client{Timeout: 10 * time.Second}
+
+var (
+	errOIDCDisabled = errors.New("вход через OIDC не настроен")
+	errOIDCUser     = errors.New("учётная запись провайдера не сопоставлена пользователю")
+)
+
+// Настройки входа через OIDC из переменных окружения
+type oidcConfig struct {
+	issuer       string // TODO_OIDC_ISSUER
+	clientID     string // TODO_OIDC_CLIENT_ID
+	clientSecret string // TODO_OIDC_CLIENT_SECRET
+	redirectURL  string // TODO_OIDC_REDIRECT_URL
+	loginClaim   string // TODO_OIDC_LOGIN_CLAIM
+	autoCreate   bool   // TODO_OIDC_AUTO_CREATE
+}
+
+// Вход через OIDC включён, если заданы издатель и идентификатор клиента
+func currentOIDCConfig() (oidcConfig, error) {
+	config := oidcConfig{
+		issuer:       strings.TrimSuffix(os.Getenv("TODO_OIDC_ISSUER"), "/"),
+		clientID:     os.Getenv("TODO_OIDC_CLIENT_ID"),
+		clientSecret: os.Getenv("TODO_OIDC_CLIENT_SECRET"),
+		redirectURL:  os.Getenv("TODO_OIDC_REDIRECT_URL"),
+		loginClaim:   os.Getenv("TODO_OIDC_LOGIN_CLAIM"),
+		autoCreate:   os.Getenv("TODO_OIDC_AUTO_CREATE") == "true",
+	}
+	if config.issuer == "" || config.clientID == "" {
+		return oidcConfig{}, errOIDCDisabled
+	}
+	if config.loginClaim == "" {
+		config.loginClaim = "preferred_username"
+	}
+	return config, nil
+}
+
+// Адрес возврата от провайдера: из настроек или по адресу, на который пришёл запрос
+func (c oidcConfig) callbackURL(r *http.Request) string {
+	if c.redirectURL != "" {
+		return c.redirectURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/api/oidc/callback"
+}
+
+// Метаданные провайдера из /.well-known/openid-configuration
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Начатый вход: ждём возврата пользователя от провайдера
+type oidcLogin struct {
+	verifier string // code_verifier для PKCE
+	nonce    string
+	expires  time.Time
+}
+
+// Вход, ожидающий кода второго фактора: провайдер пользователя уже подтвердил
+type oidcChallenge struct {
+	userID  int64
+	expires time.Time
+}
+
+// Кеш метаданных и ключей провайдера, начатые входы и входы, ожидающие второго фактора
+type oidcState struct {
+	mu          sync.Mutex
+	issuer      string
+	provider    *oidcProvider
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+	logins      map[string]oidcLogin
+	challenges  map[string]oidcChallenge
+}
+
+var oidc = &oidcState{logins: map[string]oidcLogin{}, challenges: map[string]oidcChallenge{}}
+
+// Загружаем JSON по адресу провайдера
+func fetchJSON(address string, v any) error {
+	resp, err := oidcClient.Get(address)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status %s", address, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Возвращаем метаданные провайдера, при первом обращении выполняя discovery
+func (s *oidcState) discover(issuer string) (*oidcProvider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.provider != nil && s.issuer == issuer {
+		return s.provider, nil
+	}
+
+	provider := &oidcProvider{}
+	if err := fetchJSON(issuer+"/.well-known/openid-configuration", provider); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery issuer %q does not match %q", provider.Issuer, issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document of %s is incomplete", issuer)
+	}
+
+	s.issuer = issuer
+	s.provider = provider
+	s.keys = nil
+	return provider, nil
+}
+
+// Возвращаем открытый ключ провайдера для проверки подписи ID токена.
+// Ключи перезагружаются, если встретился неизвестный kid: провайдер мог их сменить
+func (s *oidcState) publicKey(provider *oidcProvider, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	if s.keys != nil && time.Since(s.keysFetched) < oidcKeysRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+
+	if err := s.fetchKeys(provider); err != nil {
+		return nil, err
+	}
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key: %q", kid)
+}
+
+// Перезагружаем ключи провайдера, если с прошлой загрузки прошло не меньше
+// oidcKeysReloadInterval, и сообщаем, загружены ли ключи заново
+func (s *oidcState) reloadKeys(provider *oidcProvider) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys != nil && time.Since(s.keysFetched) < oidcKeysReloadInterval {
+		return false
+	}
+	if err := s.fetchKeys(provider); err != nil {
+		log.Printf("OIDC keys reload failed: %v", err)
+		return false
+	}
+	return true
+}
+
+// Загружаем ключи провайдера из JWKS. Вызывается под s.mu
+func (s *oidcState) fetchKeys(provider *oidcProvider) error {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := fetchJSON(provider.JWKSURI, &set); err != nil {
+		return err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) > 4 {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	s.keys = keys
+	s.keysFetched = time.Now()
+	return nil
+}
+
+// Запоминаем начатый вход, заодно удаляя просроченные
+func (s *oidcState) start(state string, login oidcLogin) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for key, l := range s.logins {
+		if now.After(l.expires) {
+			delete(s.logins, key)
+		}
+	}
+	s.logins[state] = login
+}
+
+// Забираем начатый вход. Каждый state можно использовать один раз
+func (s *oidcState) finish(state string) (oidcLogin, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	login, ok := s.logins[state]
+	delete(s.logins, state)
+	if !ok || time.Now().After(login.expires) {
+		return oidcLogin{}, false
+	}
+	return login, true
+}
+
+// Запоминаем вход, ожидающий второго фактора, заодно удаляя просроченные
+func (s *oidcState) startChallenge(id string, challenge oidcChallenge) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for key, c := range s.challenges {
+		if now.After(c.expires) {
+			delete(s.challenges, key)
+		}
+	}
+	s.challenges[id] = challenge
+}
+
+// Возвращаем вход, ожидающий второго фактора. Он остаётся доступным, пока код не принят,
+// а число попыток ограничивает signins, как и при входе по паролю
+func (s *oidcState) challenge(id string) (oidcChallenge, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	challenge, ok := s.challenges[id]
+	if !ok || time.Now().After(challenge.expires) {
+		delete(s.challenges, id)
+		return oidcChallenge{}, false
+	}
+	return challenge, true
+}
+
+// Завершаем вход, ожидавший второго фактора. Каждый вход завершается один раз
+func (s *oidcState) finishChallenge(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.challenges[id]
+	delete(s.challenges, id)
+	return ok
+}
+
+// OIDCLoginHandler начинает вход через провайдера OIDC: запоминает state, nonce
+// и code_verifier (PKCE) и перенаправляет пользователя на страницу провайдера
+func OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	config, err := currentOIDCConfig()
+	if err != nil {
+		apierr.Write(w, r, http.StatusNotFound, "Вход через OIDC не настроен")
+		return
+	}
+	provider, err := oidc.discover(config.issuer)
+	if err != nil {
+		log.Printf("OIDC discovery failed: %v", err)
+		apierr.Write(w, r, http.StatusBadGateway, "Провайдер OIDC недоступен")
+		return
+	}
+
+	state, err := randomHex(16)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при начале входа")
+		return
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при начале входа")
+		return
+	}
+	verifier, err := randomHex(32)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при начале входа")
+		return
+	}
+	oidc.start(state, oidcLogin{verifier: verifier, nonce: nonce, expires: time.Now().Add(oidcLoginTimeout)})
+
+	// state привязывается к браузеру, чтобы чужой ответ провайдера нельзя было подсунуть пользователю
+	http.SetCookie(w, &http.Cookie{
+		Name:     "oidc_state",
+		Value:    state,
+		Path:     "/api/oidc",
+		MaxAge:   int(oidcLoginTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   secureCookies(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", config.clientID)
+	query.Set("redirect_uri", config.callbackURL(r))
+	query.Set("scope", "openid profile email")
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	http.Redirect(w, r, provider.AuthorizationEndpoint+separator+query.Encode(), http.StatusFound)
+}
+
+// OIDCCallbackHandler завершает вход: обменивает код на ID токен, проверяет его,
+// находит пользователя и открывает сессию, после чего перенаправляет на главную страницу.
+// Если у пользователя подключён второй фактор, сессия откроется только после проверки
+// кода в OIDCTwoFactorHandler, а браузер перенаправляется на страницу входа
+func OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	config, err := currentOIDCConfig()
+	if err != nil {
+		apierr.Write(w, r, http.StatusNotFound, "Вход через OIDC не настроен")
+		return
+	}
+	query := r.URL.Query()
+	if providerError := query.Get("error"); providerError != "" {
+		log.Printf("OIDC provider returned error: %s", providerError)
+		apierr.Write(w, r, http.StatusUnauthorized, "Провайдер OIDC отклонил вход")
+		return
+	}
+
+	state := query.Get("state")
+	stateCookie, err := r.Cookie("oidc_state")
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(stateCookie.Value), []byte(state)) != 1 {
+		apierr.Write(w, r, http.StatusBadRequest, "Недействительный запрос входа")
+		return
+	}
+	login, ok := oidc.finish(state)
+	if !ok {
+		apierr.Write(w, r, http.StatusBadRequest, "Недействительный запрос входа")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     "oidc_state",
+		Value:    "",
+		Path:     "/api/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secureCookies(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	provider, err := oidc.discover(config.issuer)
+	if err != nil {
+		log.Printf("OIDC discovery failed: %v", err)
+		apierr.Write(w, r, http.StatusBadGateway, "Провайдер OIDC недоступен")
+		return
+	}
+
+	rawIDToken, err := exchangeOIDCCode(provider, config, r, query.Get("code"), login.verifier)
+	if err != nil {
+		log.Printf("OIDC code exchange failed: %v", err)
+		apierr.Write(w, r, http.StatusBadGateway, "Не удалось получить токен у провайдера OIDC")
+		return
+	}
+
+	claims, err := verifyIDToken(provider, config, rawIDToken, login.nonce)
+	if err != nil {
+		log.Printf("OIDC ID token rejected: %v", err)
+		apierr.Write(w, r, http.StatusUnauthorized, "Недействительный ID токен")
+		return
+	}
+
+	user, err := oidcUser(config, claims)
+	if errors.Is(err, errOIDCUser) {
+		log.Printf("OIDC sign-in rejected for %v: %v", claims["sub"], err)
+		apierr.Write(w, r, http.StatusForbidden, "Учётная запись не найдена")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении пользователя")
+		return
+	}
+
+	userID := userIDOf(user)
+
+	// Как и при входе по паролю, второй фактор проверяется, если пользователь его подключил.
+	// Без него сессию ограничит AuthMiddleware, если администратор требует второй фактор
+	if user.TOTPEnabled {
+		challenge, err := randomHex(16)
+		if err != nil {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при создании сессии")
+			return
+		}
+		oidc.startChallenge(challenge, oidcChallenge{userID: userID, expires: time.Now().Add(oidcChallengeTimeout)})
+		http.SetCookie(w, &http.Cookie{
+			Name:     "oidc_challenge",
+			Value:    challenge,
+			Path:     "/api/oidc",
+			MaxAge:   int(oidcChallengeTimeout.Seconds()),
+			HttpOnly: true,
+			Secure:   secureCookies(r),
+			SameSite: http.SameSiteStrictMode,
+		})
+		http.Redirect(w, r, "/login.html?two_factor=oidc", http.StatusFound)
+		return
+	}
+
+	sessionID, _, err := createSession(r, userID)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при создании сессии")
+		return
+	}
+	if _, err := setTokenCookie(w, r, userID, sessionID); err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при создании токена")
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// OIDCTwoFactorHandler завершает вход через провайдера у пользователя со вторым фактором:
+// проверяет код TOTP или код восстановления и открывает сессию так же, как SigninHandler
+func OIDCTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	cookie, err := r.Cookie("oidc_challenge")
+	if err != nil {
+		apierr.Write(w, r, http.StatusUnauthorized, "Вход через провайдера не начат или устарел")
+		return
+	}
+	challenge, ok := oidc.challenge(cookie.Value)
+	if !ok {
+		apierr.Write(w, r, http.StatusUnauthorized, "Вход через провайдера не начат или устарел")
+		return
+	}
+	user, err := db.GetUserByID(challenge.userID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		apierr.Write(w, r, http.StatusUnauthorized, "Вход через провайдера не начат или устарел")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении пользователя")
+		return
+	}
+
+	ip := clientIP(r)
+	if wait := signins.retryAfter(user.Login, ip); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		apierr.Write(w, r, http.StatusTooManyRequests, "Слишком много попыток входа, попробуйте позже")
+		return
+	}
+	if request.Code == "" && request.RecoveryCode == "" {
+		apierr.WriteCode(w, r, http.StatusUnauthorized, apierr.CodeTwoFactorRequired, "Требуется код двухфакторной аутентификации")
+		return
+	}
+	ok, err = checkSecondFactor(user, request.Code, request.RecoveryCode)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при проверке кода")
+		return
+	}
+	if !ok {
+		failures := signins.fail(user.Login, ip)
+		log.Printf("Failed second factor for %q from %s after OIDC sign-in (%d in a row)", user.Login, ip, failures)
+		apierr.WriteCode(w, r, http.StatusUnauthorized, apierr.CodeInvalidTwoFactorCode, "Неверный код двухфакторной аутентификации")
+		return
+	}
+	if !oidc.finishChallenge(cookie.Value) {
+		apierr.Write(w, r, http.StatusUnauthorized, "Вход через провайдера не начат или устарел")
+		return
+	}
+	signins.succeed(user.Login)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "oidc_challenge",
+		Value:    "",
+		Path:     "/api/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secureCookies(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+	startSession(w, r, userIDOf(user))
+}
+
+// Обмениваем код авторизации на ID токен, подтверждая вход через code_verifier
+func exchangeOIDCCode(provider *oidcProvider, config oidcConfig, r *http.Request, code, verifier string) (string, error) {
+	if code == "" {
+		return "", errors.New("missing authorization code")
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", config.callbackURL(r))
+	form.Set("client_id", config.clientID)
+	form.Set("code_verifier", verifier)
+	if config.clientSecret != "" {
+		form.Set("client_secret", config.clientSecret)
+	}
+
+	resp, err := oidcClient.PostForm(provider.TokenEndpoint, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint: unexpected status %s", resp.Status)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return "", err
+	}
+	if tokens.IDToken == "" {
+		return "", errors.New("token endpoint did not return id_token")
+	}
+	return tokens.IDToken, nil
+}
+
+// Проверяем подпись ID токена ключом провайдера (RS256), издателя, получателя,
+// срок действия и nonce начатого входа
+func verifyIDToken(provider *oidcProvider, config oidcConfig, rawIDToken, nonce string) (jwt.MapClaims, error) {
+	parse := func() (*jwt.Token, jwt.MapClaims, error) {
+		claims := jwt.MapClaims{}
+		token, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+			if token.Method != jwt.SigningMethodRS256 {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			kid, _ := token.Header["kid"].(string)
+			return oidc.publicKey(provider, kid)
+		})
+		return token, claims, err
+	}
+
+	token, claims, err := parse()
+	// Подпись не совпала с ключом из кеша: перезагружаем ключи один раз и проверяем снова
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0 && oidc.reloadKeys(provider) {
+		token, claims, err = parse()
+	}
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("invalid signature or expired: %v", err)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("missing exp")
+	}
+
+	if issuer, _ := claims["iss"].(string); strings.TrimSuffix(issuer, "/") != config.issuer {
+		return nil, fmt.Errorf("unexpected issuer %q", issuer)
+	}
+
+	// aud может быть строкой или списком
+	var audience []string
+	switch aud := claims["aud"].(type) {
+	case string:
+		audience = []string{aud}
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				audience = append(audience, s)
+			}
+		}
+	}
+	found := false
+	for _, a := range audience {
+		found = found || a == config.clientID
+	}
+	if !found {
+		return nil, fmt.Errorf("token is not issued for client %q", config.clientID)
+	}
+	if azp, ok := claims["azp"].(string); ok && azp != config.clientID {
+		return nil, fmt.Errorf("unexpected authorized party %q", azp)
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, errors.New("nonce mismatch")
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, errors.New("missing sub")
+	}
+	return claims, nil
+}
+
+// Находим пользователя, связанного с учётной записью провайдера. Связь задаёт
+// администратор, так как утверждения провайдера вроде логина может изменить сам
+// пользователь. Если связанного пользователя нет и включено TODO_OIDC_AUTO_CREATE,
+// создаётся новый участник с логином из утверждения TODO_OIDC_LOGIN_CLAIM
+func oidcUser(config oidcConfig, claims jwt.MapClaims) (db.User, error) {
+	sub, _ := claims["sub"].(string)
+	subject := config.issuer + "|" + sub
+
+	user, err := db.GetUserByOIDCSubject(subject)
+	if !errors.Is(err, db.ErrUserNotFound) {
+		return user, err
+	}
+	if !config.autoCreate {
+		return db.User{}, fmt.Errorf("%w: account %s is not linked", errOIDCUser, subject)
+	}
+
+	login, _ := claims[config.loginClaim].(string)
+	login = strings.TrimSpace(login)
+	if login == "" || utf8.RuneCountInString(login) > 64 || strings.ContainsAny(login, " \t\n") {
+		return db.User{}, fmt.Errorf("%w: claim %q is missing or invalid", errOIDCUser, config.loginClaim)
+	}
+
+	// Существующего пользователя с тем же логином не связываем: это сделает администратор
+	password, err := randomHex(32)
+	if err != nil {
+		return db.User{}, err
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return db.User{}, err
+	}
+	id, err := db.AddUser(db.User{Login: login, Role: db.RoleMember, PasswordHash: hash})
+	if errors.Is(err, db.ErrUserExists) {
+		return db.User{}, fmt.Errorf("%w: user %q exists but is not linked to %s", errOIDCUser, login, subject)
+	} else if err != nil {
+		return db.User{}, err
+	}
+	if err := db.SetOIDCSubject(id, subject); err != nil {
+		return db.User{}, err
+	}
+	log.Printf("Created user %q for OIDC account %s", login, subject)
+	return db.GetUserByID(id)
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Сколько записей о неудачных попытках хранить, прежде чем удалять устаревшие
+const maxTrackedSignins = 10000
+
+// Неудачные попытки входа по одному ключу (логину или IP-адресу)
+type signinAttempts struct {
+	failures    int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// Ограничитель попыток входа. После превышения числа неудачных попыток
+// ключ блокируется, и каждая следующая неудача удваивает срок блокировки
+type signinLimiter struct {
+	mu      sync.Mutex
+	entries map[string]*signinAttempts
+}
+
+var signins = &signinLimiter{entries: map[string]*signinAttempts{}}
+
+// Настройки ограничителя из переменных окружения
+type signinLimits struct {
+	maxAccount int           // TODO_SIGNIN_MAX_ATTEMPTS
+	maxIP      int           // TODO_SIGNIN_MAX_ATTEMPTS_IP
+	lockout    time.Duration // TODO_SIGNIN_LOCKOUT
+	maxLockout time.Duration // TODO_SIGNIN_MAX_LOCKOUT
+}
+
+func currentSigninLimits() signinLimits {
+	return signinLimits{
+		maxAccount: envInt("TODO_SIGNIN_MAX_ATTEMPTS", 5),
+		maxIP:      envInt("TODO_SIGNIN_MAX_ATTEMPTS_IP", 50),
+		lockout:    envDuration("TODO_SIGNIN_LOCKOUT", time.Minute),
+		maxLockout: envDuration("TODO_SIGNIN_MAX_LOCKOUT", time.Hour),
+	}
+}
+
+func envInt(name string, def int) int {
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil && value > 0 {
+		return value
+	}
+	return def
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(name)); err == nil && value > 0 {
+		return value
+	}
+	return def
+}
+
+// Возвращаем запись о попытках, сбрасывая её, если неудач давно не было.
+// Вызывается под блокировкой
+func (l *signinLimiter) entry(key string, now time.Time, limits signinLimits) *signinAttempts {
+	e, ok := l.entries[key]
+	if !ok || (now.After(e.lockedUntil) && now.Sub(e.last) > limits.maxLockout) {
+		e = &signinAttempts{}
+		l.entries[key] = e
+	}
+	return e
+}
+
+// Проверяем, заблокированы ли логин или IP-адрес, и возвращаем, сколько ждать до снятия блокировки
+func (l *signinLimiter) retryAfter(login, ip string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range []string{"login:" + login, "ip:" + ip} {
+		if e, ok := l.entries[key]; ok && e.lockedUntil.Sub(now) > wait {
+			wait = e.lockedUntil.Sub(now)
+		}
+	}
+	return wait
+}
+
+// Учитываем неудачную попытку и возвращаем число неудач подряд для логина
+func (l *signinLimiter) fail(login, ip string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	limits := currentSigninLimits()
+	if len(l.entries) > maxTrackedSignins {
+		l.prune(now, limits)
+	}
+
+	var failures int
+	for _, key := range []string{"login:" + login, "ip:" + ip} {
+		limit := limits.maxAccount
+		if key == "ip:"+ip {
+			limit = limits.maxIP
+		}
+
+		e := l.entry(key, now, limits)
+		e.failures++
+		e.last = now
+		if e.failures >= limit {
+			e.lockedUntil = now.Add(lockoutDuration(e.failures-limit, limits))
+		}
+		if key == "login:"+login {
+			failures = e.failures
+		}
+	}
+	return failures
+}
+
+// Срок блокировки удваивается с каждой неудачей сверх допустимого числа
+func lockoutDuration(extra int, limits signinLimits) time.Duration {
+	d := float64(limits.lockout) * math.Pow(2, float64(extra))
+	if d > float64(limits.maxLockout) {
+		return limits.maxLockout
+	}
+	return time.Duration(d)
+}
+
+// После успешного входа неудачные попытки для логина забываются.
+// Счётчик IP-адреса сбрасывается только со временем, чтобы вход в свою
+// учётную запись не позволял перебирать пароли чужих
+func (l *signinLimiter) succeed(login string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, "login:"+login)
+}
+
+// Удаляем устаревшие записи. Вызывается под блокировкой
+func (l *signinLimiter) prune(now time.Time, limits signinLimits) {
+	for key, e := range l.entries {
+		if now.After(e.lockedUntil) && now.Sub(e.last) > limits.maxLockout {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"todo-app/apierr"
+	"todo-app/db"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Срок жизни сессии без обновления: каждое обновление токена продлевает его заново
+const sessionLifetime = 30 * 24 * time.Hour
+
+// Максимальная длина сохраняемого заголовка User-Agent
+const maxUserAgentLength = 256
+
+// Генерируем случайную строку из n байт в шестнадцатеричном виде
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// В базе хранится только хеш refresh-токена или персонального токена
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Определяем адрес клиента по соединению
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Открываем новую сессию пользователя и отдаём клиенту токен доступа и refresh-токен
+func startSession(w http.ResponseWriter, r *http.Request, userID int64) {
+	sessionID, secret, err := createSession(r, userID)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при создании сессии")
+		return
+	}
+
+	writeTokens(w, r, userID, sessionID, secret)
+}
+
+// Сохраняем новую сессию и возвращаем её идентификатор и секрет refresh-токена
+func createSession(r *http.Request, userID int64) (string, string, error) {
+	sessionID, err := randomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	err = db.AddSession(db.Session{
+		ID:          sessionID,
+		UserID:      userID,
+		RefreshHash: hashToken(secret),
+		UserAgent:   userAgent,
+		IP:          clientIP(r),
+		ExpiresAt:   time.Now().Add(sessionLifetime).UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return sessionID, secret, nil
+}
+
+// Подписываем токен доступа для сессии и сохраняем его в куке
+func setTokenCookie(w http.ResponseWriter, r *http.Request, userID int64, sessionID string) (string, error) {
+	expirationTime := time.Now().Add(tokenLifetime)
+	claims := &Claims{
+		Authorized: true,
+		UserID:     userID,
+		SessionID:  sessionID,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expirationTime.Unix(),
+		},
+	}
+
+	tokenString, err := signToken(claims)
+	if err != nil {
+		return "", err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "token",
+		Value:    tokenString,
+		Path:     "/",
+		Expires:  expirationTime,
+		HttpOnly: true,
+		Secure:   secureCookies(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return tokenString, nil
+}
+
+// Выдаём токен доступа для сессии и refresh-токен вида <сессия>.<секрет>
+func writeTokens(w http.ResponseWriter, r *http.Request, userID int64, sessionID, secret string) {
+	tokenString, err := setTokenCookie(w, r, userID, sessionID)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при создании токена")
+		return
+	}
+
+	response := map[string]string{"token": tokenString, "refresh_token": sessionID + "." + secret}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RefreshHandler выдаёт новый токен доступа по refresh-токену и продлевает сессию.
+// Refresh-токен одноразовый: повторное предъявление старого токена отзывает сессию
+func RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	sessionID, secret, ok := strings.Cut(request.RefreshToken, ".")
+	if !ok {
+		apierr.Write(w, r, http.StatusUnauthorized, "Недействительный refresh-токен")
+		return
+	}
+
+	session, err := db.GetSession(sessionID)
+	if errors.Is(err, db.ErrSessionNotFound) {
+		apierr.Write(w, r, http.StatusUnauthorized, "Недействительный refresh-токен")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении сессии")
+		return
+	}
+
+	oldHash := hashToken(secret)
+	if subtle.ConstantTimeCompare([]byte(oldHash), []byte(session.RefreshHash)) != 1 {
+		// Старый refresh-токен мог быть украден, поэтому сессию закрываем целиком
+		if err := db.DeleteSession(session.ID, session.UserID); err != nil {
+			log.Printf("Failed to revoke session %s: %v", session.ID, err)
+		}
+		apierr.Write(w, r, http.StatusUnauthorized, "Недействительный refresh-токен")
+		return
+	}
+
+	if _, err := db.GetUserByID(session.UserID); errors.Is(err, db.ErrUserNotFound) {
+		apierr.Write(w, r, http.StatusUnauthorized, "Недействительный refresh-токен")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении пользователя")
+		return
+	}
+
+	newSecret, err := randomHex(32)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при обновлении сессии")
+		return
+	}
+	expiresAt := time.Now().Add(sessionLifetime).UTC().Format(time.RFC3339)
+	err = db.RefreshSession(session.ID, oldHash, hashToken(newSecret), expiresAt)
+	if errors.Is(err, db.ErrSessionNotFound) {
+		apierr.Write(w, r, http.StatusUnauthorized, "Недействительный refresh-токен")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при обновлении сессии")
+		return
+	}
+
+	writeTokens(w, r, session.UserID, session.ID, newSecret)
+}
+
+// SignoutHandler закрывает текущую сессию и удаляет куку с токеном
+func SignoutHandler(w http.ResponseWriter, r *http.Request) {
+	// Без пароля сессии не создаются, поэтому закрывать нечего
+	if sessionID := CurrentSessionID(r); sessionID != "" {
+		err := db.DeleteSession(sessionID, UserID(r))
+		if err != nil && !errors.Is(err, db.ErrSessionNotFound) {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при закрытии сессии")
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "token",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secureCookies(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{})
+}
+
+// SessionsHandler возвращает активные сессии пользователя (GET) и отзывает сессию по id (DELETE)
+func SessionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		sessions, err := db.GetSessions(UserID(r))
+		if err != nil {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении сессий")
+			return
+		}
+		if sessions == nil {
+			sessions = []db.Session{}
+		}
+		for i := range sessions {
+			sessions[i].Current = sessions[i].ID == CurrentSessionID(r)
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		json.NewEncoder(w).Encode(map[string]interface{}{"sessions": sessions})
+	case http.MethodDelete:
+		err := db.DeleteSession(r.URL.Query().Get("id"), UserID(r))
+		if errors.Is(err, db.ErrSessionNotFound) {
+			apierr.Write(w, r, http.StatusNotFound, "сессия не найдена")
+			return
+		} else if err != nil {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при закрытии сессии")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		json.NewEncoder(w).Encode(map[string]string{})
+	default:
+		apierr.Write(w, r, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// CurrentSessionID возвращает идентификатор сессии, из которой выполняется запрос
+func CurrentSessionID(r *http.Request) string {
+	sessionID, _ := r.Context().Value(sessionContextKey{}).(string)
+	return sessionID
+}
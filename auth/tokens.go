@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"todo-app/apierr"
+	"todo-app/db"
+	"todo-app/i18n"
+	"unicode/utf8"
+)
+
+// Персональные токены доступа начинаются с этого префикса,
+// по нему они отличаются от JWT
+const apiTokenPrefix = "todo_pat_"
+
+// Области действия персональных токенов
+const (
+	ScopeReadOnly   = "read-only"   // только чтение (GET)
+	ScopeTasksWrite = "tasks:write" // чтение и изменение задач, проектов и меток
+	ScopeAdmin      = "admin"       // всё, включая администрирование
+)
+
+// Максимальный срок действия персонального токена в днях
+const maxAPITokenDays = 3650
+
+// Извлекаем токен из заголовка Authorization: Bearer <токен>
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// Проверяем, покрывает ли набор областей требуемую.
+// Область admin включает все остальные, tasks:write включает чтение
+func scopeAllows(scopes []string, required string) bool {
+	for _, scope := range scopes {
+		switch {
+		case scope == required, scope == ScopeAdmin:
+			return true
+		case scope == ScopeTasksWrite && required == ScopeReadOnly:
+			return true
+		}
+	}
+	return false
+}
+
+// Проверяем область токена, с которым выполняется запрос.
+// Запросы из сессии и без пароля ограничиваются только ролью пользователя
+func hasScope(r *http.Request, required string) bool {
+	scopes, ok := r.Context().Value(scopesContextKey{}).([]string)
+	if !ok {
+		return true
+	}
+	return scopeAllows(scopes, required)
+}
+
+// IsAPIToken сообщает, выполняется ли запрос с персональным токеном доступа
+func IsAPIToken(r *http.Request) bool {
+	_, ok := r.Context().Value(scopesContextKey{}).([]string)
+	return ok
+}
+
+// Проверяем персональный токен и передаём запрос дальше от имени его владельца.
+// Запросы на чтение требуют области read-only, остальные — tasks:write
+func serveAPIToken(w http.ResponseWriter, r *http.Request, next http.Handler, tokenString string) {
+	token, err := db.UseAPIToken(hashToken(tokenString))
+	if errors.Is(err, db.ErrAPITokenNotFound) {
+		apierr.Write(w, r, http.StatusUnauthorized, "Требуется аутентификация")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при проверке токена")
+		return
+	}
+
+	user, err := db.GetUserByID(token.UserID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		apierr.Write(w, r, http.StatusUnauthorized, "Требуется аутентификация")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении пользователя")
+		return
+	}
+
+	r = r.WithContext(withUser(r.Context(), user))
+	if !checkSecondFactorRequirement(w, r, user) {
+		return
+	}
+
+	required := ScopeTasksWrite
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		required = ScopeReadOnly
+	}
+	if !scopeAllows(token.Scopes, required) {
+		apierr.WriteCode(w, r, http.StatusForbidden, apierr.CodeInsufficientScope, "Недостаточно прав токена")
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), scopesContextKey{}, token.Scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// TokensHandler управляет персональными токенами пользователя:
+// GET — список, POST — выпуск нового токена, DELETE ?id= — отзыв.
+// Выпускать и отзывать токены можно только из сессии, но не другим токеном
+func TokensHandler(w http.ResponseWriter, r *http.Request) {
+	if IsAPIToken(r) {
+		apierr.Write(w, r, http.StatusForbidden, "Управление токенами доступно только после входа")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		tokens, err := db.GetAPITokens(UserID(r))
+		if err != nil {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении токенов")
+			return
+		}
+		if tokens == nil {
+			tokens = []db.APIToken{}
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		json.NewEncoder(w).Encode(map[string]interface{}{"tokens": tokens})
+	case http.MethodPost:
+		handleCreateAPIToken(w, r)
+	case http.MethodDelete:
+		id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		if err != nil {
+			apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+			return
+		}
+		err = db.DeleteAPIToken(id, UserID(r))
+		if errors.Is(err, db.ErrAPITokenNotFound) {
+			apierr.Write(w, r, http.StatusNotFound, "токен не найден")
+			return
+		} else if err != nil {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при отзыве токена")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		json.NewEncoder(w).Encode(map[string]string{})
+	default:
+		apierr.Write(w, r, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// Выпускаем персональный токен. Сам токен возвращается только в этом ответе
+func handleCreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var request struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" || utf8.RuneCountInString(request.Name) > 128 {
+		apierr.WriteField(w, r, "name", "Не указано название токена")
+		return
+	}
+
+	if len(request.Scopes) == 0 {
+		apierr.WriteField(w, r, "scopes", "Не указаны области действия токена")
+		return
+	}
+	for _, scope := range request.Scopes {
+		if scope != ScopeReadOnly && scope != ScopeTasksWrite && scope != ScopeAdmin {
+			apierr.WriteField(w, r, "scopes", i18n.Sprintf(r, "Некорректная область действия: %s", scope))
+			return
+		}
+		// Токен не может давать больше прав, чем есть у пользователя
+		role := CurrentUser(r).Role
+		if (scope == ScopeAdmin && role != db.RoleAdmin) || (scope == ScopeTasksWrite && role == db.RoleReadOnly) {
+			apierr.Write(w, r, http.StatusForbidden, "Недостаточно прав")
+			return
+		}
+	}
+
+	if request.ExpiresInDays < 0 || request.ExpiresInDays > maxAPITokenDays {
+		apierr.WriteField(w, r, "expires_in_days", i18n.Sprintf(r, "Срок действия должен быть от 0 до %d дней", maxAPITokenDays))
+		return
+	}
+	var expiresAt string
+	if request.ExpiresInDays > 0 {
+		expiresAt = time.Now().AddDate(0, 0, request.ExpiresInDays).UTC().Format(time.RFC3339)
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при создании токена")
+		return
+	}
+	tokenString := apiTokenPrefix + secret
+
+	id, err := db.AddAPIToken(db.APIToken{
+		UserID:    UserID(r),
+		Name:      request.Name,
+		Prefix:    tokenString[:len(apiTokenPrefix)+8],
+		Scopes:    request.Scopes,
+		ExpiresAt: expiresAt,
+		TokenHash: hashToken(tokenString),
+	})
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при создании токена")
+		return
+	}
+
+	response := map[string]string{"id": fmt.Sprintf("%d", id), "token": tokenString}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
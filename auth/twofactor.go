@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"todo-app/apierr"
+	"todo-app/db"
+)
+
+// Параметры TOTP (RFC 6238) в том виде, который понимают приложения-аутентификаторы
+const (
+	totpIssuer = "TODO"
+	totpPeriod = 30 // секунд
+	totpDigits = 6
+	totpSkew   = 1 // сколько соседних интервалов принимается из-за расхождения часов
+)
+
+// Сколько кодов восстановления выдаётся при включении двухфакторной аутентификации
+const recoveryCodeCount = 10
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Вычисляем код TOTP для интервала
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+// Проверяем код TOTP пользователя. Принятый код нельзя использовать повторно
+func verifyTOTP(user db.User, code string) (bool, error) {
+	secret, err := totpEncoding.DecodeString(user.TOTPSecret)
+	if err != nil || len(secret) == 0 {
+		return false, err
+	}
+	code = strings.TrimSpace(code)
+
+	now := time.Now().Unix() / totpPeriod
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, step)), []byte(code)) == 1 {
+			return db.UseTOTPStep(userIDOf(user), step)
+		}
+	}
+	return false, nil
+}
+
+// Коды восстановления вводятся вручную, поэтому дефисы, пробелы и регистр не учитываются
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// Проверяем второй фактор при входе: код TOTP или код восстановления
+func checkSecondFactor(user db.User, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		return db.UseRecoveryCode(userIDOf(user), hashToken(normalizeRecoveryCode(recoveryCode)))
+	}
+	return verifyTOTP(user, code)
+}
+
+// Проверяем, требует ли администратор двухфакторную аутентификацию от всех пользователей
+func secondFactorRequired() (bool, error) {
+	value, err := db.GetSetting(db.SettingRequire2FA)
+	return value == "true", err
+}
+
+// Пока пользователь не подключил обязательную двухфакторную аутентификацию,
+// ему доступны только её настройка и выход
+func secondFactorAllowed(r *http.Request, user db.User) (bool, error) {
+	if user.TOTPEnabled || strings.HasPrefix(r.URL.Path, "/api/2fa") || r.URL.Path == "/api/signout" {
+		return true, nil
+	}
+	required, err := secondFactorRequired()
+	return !required, err
+}
+
+// TwoFactorHandler возвращает состояние двухфакторной аутентификации пользователя
+func TwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	required, err := secondFactorRequired()
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении настроек")
+		return
+	}
+	left, err := db.CountRecoveryCodes(UserID(r))
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении кодов восстановления")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled":             user.TOTPEnabled,
+		"required":            required,
+		"recovery_codes_left": left,
+	})
+}
+
+// TwoFactorSetupHandler создаёт новый секрет TOTP и возвращает его вместе с otpauth URI
+// для QR-кода. Двухфакторная аутентификация включается после подтверждения кодом
+func TwoFactorSetupHandler(w http.ResponseWriter, r *http.Request) {
+	if IsAPIToken(r) {
+		apierr.Write(w, r, http.StatusForbidden, "Настройка двухфакторной аутентификации доступна только после входа")
+		return
+	}
+	user := CurrentUser(r)
+	if user.TOTPEnabled {
+		apierr.Write(w, r, http.StatusConflict, "Двухфакторная аутентификация уже включена")
+		return
+	}
+
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при создании секрета")
+		return
+	}
+	encoded := totpEncoding.EncodeToString(secret)
+	if err := db.SetTOTPSecret(UserID(r), encoded); err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при сохранении секрета")
+		return
+	}
+
+	query := url.Values{}
+	query.Set("secret", encoded)
+	query.Set("issuer", totpIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprintf("%d", totpDigits))
+	query.Set("period", fmt.Sprintf("%d", totpPeriod))
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + totpIssuer + ":" + user.Login,
+		RawQuery: query.Encode(),
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{"secret": encoded, "uri": uri.String()})
+}
+
+// TwoFactorEnableHandler включает двухфакторную аутентификацию после проверки кода
+// из приложения и возвращает коды восстановления. Коды показываются только один раз
+func TwoFactorEnableHandler(w http.ResponseWriter, r *http.Request) {
+	if IsAPIToken(r) {
+		apierr.Write(w, r, http.StatusForbidden, "Настройка двухфакторной аутентификации доступна только после входа")
+		return
+	}
+	var request struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	user := CurrentUser(r)
+	if user.TOTPEnabled {
+		apierr.Write(w, r, http.StatusConflict, "Двухфакторная аутентификация уже включена")
+		return
+	}
+	if user.TOTPSecret == "" {
+		apierr.Write(w, r, http.StatusBadRequest, "Сначала получите секрет через /api/2fa/setup")
+		return
+	}
+
+	ok, err := verifyTOTP(user, request.Code)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при проверке кода")
+		return
+	}
+	if !ok {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidTwoFactorCode, "Неверный код подтверждения")
+		return
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := randomHex(5)
+		if err != nil {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при создании кодов восстановления")
+			return
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashToken(code))
+	}
+	if err := db.EnableTOTP(UserID(r), hashes); err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при включении двухфакторной аутентификации")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"recovery_codes": codes})
+}
+
+// TwoFactorDisableHandler отключает двухфакторную аутентификацию по коду из приложения
+// или коду восстановления. Если администратор её требует, отключить нельзя
+func TwoFactorDisableHandler(w http.ResponseWriter, r *http.Request) {
+	if IsAPIToken(r) {
+		apierr.Write(w, r, http.StatusForbidden, "Настройка двухфакторной аутентификации доступна только после входа")
+		return
+	}
+	var request struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	user := CurrentUser(r)
+	if !user.TOTPEnabled {
+		apierr.Write(w, r, http.StatusBadRequest, "Двухфакторная аутентификация не включена")
+		return
+	}
+	required, err := secondFactorRequired()
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении настроек")
+		return
+	}
+	if required {
+		apierr.Write(w, r, http.StatusForbidden, "Двухфакторная аутентификация обязательна")
+		return
+	}
+
+	ok, err := checkSecondFactor(user, request.Code, request.RecoveryCode)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при проверке кода")
+		return
+	}
+	if !ok {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidTwoFactorCode, "Неверный код подтверждения")
+		return
+	}
+
+	if err := db.DisableTOTP(UserID(r)); err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при отключении двухфакторной аутентификации")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{})
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"todo-app/db"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Логин встроенного администратора по умолчанию
+const defaultAdminLogin = "admin"
+
+// Определяем логин администратора из переменной окружения TODO_ADMIN_LOGIN
+func adminLogin() string {
+	if login := os.Getenv("TODO_ADMIN_LOGIN"); login != "" {
+		return login
+	}
+	return defaultAdminLogin
+}
+
+// IsBuiltinAdmin сообщает, является ли пользователь встроенным администратором.
+// Его нельзя удалить или лишить прав, чтобы в приложении всегда оставался администратор
+func IsBuiltinAdmin(user db.User) bool {
+	return user.Login == adminLogin()
+}
+
+// HashPassword возвращает bcrypt-хеш пароля
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword сравнивает пароль с его bcrypt-хешем
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// InitUsers создаёт встроенного администратора, пароль которого берётся из TODO_PASSWORD,
+// и передаёт ему данные, созданные до появления учётных записей
+func InitUsers() {
+	password, passwordExists := os.LookupEnv("TODO_PASSWORD")
+	if !passwordExists {
+		// Без пароля вход не проверяется, поэтому пароль администратора случайный
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			log.Fatalf("Failed to generate admin password: %v", err)
+		}
+		password = hex.EncodeToString(b)
+	}
+
+	admin, err := db.GetUserByLogin(adminLogin())
+	switch {
+	case errors.Is(err, db.ErrUserNotFound):
+		hash, err := HashPassword(password)
+		if err != nil {
+			log.Fatalf("Failed to hash admin password: %v", err)
+		}
+		if _, err := db.AddUser(db.User{Login: adminLogin(), Role: db.RoleAdmin, PasswordHash: hash}); err != nil {
+			log.Fatalf("Failed to create admin user: %v", err)
+		}
+		if admin, err = db.GetUserByLogin(adminLogin()); err != nil {
+			log.Fatalf("Failed to load admin user: %v", err)
+		}
+		log.Printf("Created admin user: %s", admin.Login)
+	case err != nil:
+		log.Fatalf("Failed to load admin user: %v", err)
+	case passwordExists && !CheckPassword(admin.PasswordHash, password):
+		// Пароль администратора меняется вместе с TODO_PASSWORD
+		hash, err := HashPassword(password)
+		if err != nil {
+			log.Fatalf("Failed to hash admin password: %v", err)
+		}
+		id, _ := strconv.ParseInt(admin.ID, 10, 64)
+		if err := db.SetUserPassword(id, hash); err != nil {
+			log.Fatalf("Failed to update admin password: %v", err)
+		}
+	}
+
+	id, _ := strconv.ParseInt(admin.ID, 10, 64)
+	if err := db.AssignOrphans(id); err != nil {
+		log.Fatalf("Failed to assign tasks to admin user: %v", err)
+	}
+}
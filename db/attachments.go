@@ -0,0 +1,101 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrAttachmentNotFound = errors.New("вложение не найдено")
+
+// Метаданные вложения задачи. Содержимое файла лежит в хранилище по ключу StorageKey
+type Attachment struct {
+	ID          string `json:"id"`
+	TaskID      string `json:"task_id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	CreatedAt   string `json:"created_at"`
+	StorageKey  string `json:"-"`
+}
+
+const attachmentColumns = `id, task_id, name, content_type, size, created_at, storage_key`
+
+// Считываем вложение из строки результата запроса
+func scanAttachment(row scanner) (Attachment, error) {
+	var attachment Attachment
+	var id, taskID int64
+	err := row.Scan(&id, &taskID, &attachment.Name, &attachment.ContentType, &attachment.Size, &attachment.CreatedAt, &attachment.StorageKey)
+	if err != nil {
+		return Attachment{}, err
+	}
+	attachment.ID = fmt.Sprintf("%d", id)
+	attachment.TaskID = fmt.Sprintf("%d", taskID)
+	return attachment, nil
+}
+
+// Добавляем метаданные вложения и возвращаем его идентификатор
+func AddAttachment(attachment Attachment) (int64, error) {
+	query := `INSERT INTO attachments (task_id, name, content_type, size, created_at, storage_key) VALUES (?, ?, ?, ?, ?, ?)`
+	res, err := DB.Exec(query, attachment.TaskID, attachment.Name, attachment.ContentType, attachment.Size,
+		time.Now().UTC().Format(time.RFC3339), attachment.StorageKey)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Возвращаем вложения задачи
+func GetAttachments(taskID int64) ([]Attachment, error) {
+	return getAttachments(DB, taskID)
+}
+
+// Возвращаем вложения задачи вне или внутри транзакции
+func getAttachments(q querier, taskID int64) ([]Attachment, error) {
+	rows, err := q.Query(`SELECT `+attachmentColumns+` FROM attachments WHERE task_id = ? ORDER BY id`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []Attachment
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// Возвращаем вложение по его идентификатору
+func GetAttachment(id int64) (Attachment, error) {
+	attachment, err := scanAttachment(DB.QueryRow(`SELECT `+attachmentColumns+` FROM attachments WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Attachment{}, ErrAttachmentNotFound
+	} else if err != nil {
+		return Attachment{}, err
+	}
+	return attachment, nil
+}
+
+// Удаляем метаданные вложения
+func DeleteAttachment(id int64) error {
+	res, err := DB.Exec(`DELETE FROM attachments WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrAttachmentNotFound
+	}
+	return nil
+}
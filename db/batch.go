@@ -0,0 +1,172 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TaskBatch — пакет изменений задач в одной транзакции. Каждая операция
+// видит изменения предыдущих, остальные запросы видят их только после Commit
+type TaskBatch struct {
+	tx         *sql.Tx
+	savepoints int
+	// Вложения удалённых задач: их файлы удаляются после Commit
+	attachments []Attachment
+}
+
+// BeginTaskBatch начинает пакет изменений задач
+func BeginTaskBatch() (*TaskBatch, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &TaskBatch{tx: tx}, nil
+}
+
+// Commit сохраняет все изменения пакета
+func (b *TaskBatch) Commit() error {
+	return b.tx.Commit()
+}
+
+// Rollback отменяет все изменения пакета. После Commit ничего не делает
+func (b *TaskBatch) Rollback() error {
+	err := b.tx.Rollback()
+	if errors.Is(err, sql.ErrTxDone) {
+		return nil
+	}
+	return err
+}
+
+// Savepoint выполняет fn в точке сохранения: если fn вернула ошибку,
+// отменяются только изменения fn, а пакет можно продолжать
+func (b *TaskBatch) Savepoint(fn func() error) error {
+	b.savepoints++
+	name := fmt.Sprintf("batch_%d", b.savepoints)
+	if _, err := b.tx.Exec(`SAVEPOINT ` + name); err != nil {
+		return err
+	}
+
+	attachments := len(b.attachments)
+	if err := fn(); err != nil {
+		b.attachments = b.attachments[:attachments]
+		// ROLLBACK TO оставляет точку сохранения, поэтому её тоже нужно освободить
+		if _, rollbackErr := b.tx.Exec(`ROLLBACK TO ` + name); rollbackErr != nil {
+			return rollbackErr
+		}
+		if _, releaseErr := b.tx.Exec(`RELEASE ` + name); releaseErr != nil {
+			return releaseErr
+		}
+		return err
+	}
+	_, err := b.tx.Exec(`RELEASE ` + name)
+	return err
+}
+
+// DeletedAttachments возвращает вложения задач, удалённых в пакете
+func (b *TaskBatch) DeletedAttachments() []Attachment {
+	return b.attachments
+}
+
+// GetTask возвращает задачу, доступную пользователю, вместе с её метками
+func (b *TaskBatch) GetTask(id, userID int64) (Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM scheduler WHERE id = ?`
+	task, err := scanTask(b.tx.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, ErrTaskNotFound
+	} else if err != nil {
+		return Task{}, err
+	}
+
+	if task.OwnerID != userID {
+		task.Permission, err = taskPermission(b.tx, task, userID)
+		if err != nil {
+			return Task{}, err
+		}
+	}
+
+	tasks := []Task{task}
+	if err := loadTaskTags(b.tx, tasks); err != nil {
+		return Task{}, err
+	}
+	return tasks[0], nil
+}
+
+// AddTask добавляет задачу с метками и возвращает её идентификатор
+func (b *TaskBatch) AddTask(task Task, tags []string) (int64, error) {
+	id, err := addTask(b.tx, task)
+	if err != nil {
+		return 0, err
+	}
+	if len(tags) > 0 {
+		if err := setTaskTags(b.tx, id, task.OwnerID, tags); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// UpdateTask обновляет задачу с идентификатором id и заменяет её метки
+func (b *TaskBatch) UpdateTask(id int64, task Task, tags []string) error {
+	if err := updateTask(b.tx, task); err != nil {
+		return err
+	}
+	return setTaskTags(b.tx, id, task.OwnerID, tags)
+}
+
+// RescheduleTask переносит задачу на другую дату
+func (b *TaskBatch) RescheduleTask(id, ownerID, version int64, date string) error {
+	res, err := b.tx.Exec(`UPDATE scheduler SET date = ?, version = version + 1 WHERE id = ? AND owner_id = ? AND (? = 0 OR version = ?)`,
+		date, id, ownerID, version, version)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return taskChangeError(b.tx, id, ownerID)
+	}
+	return nil
+}
+
+// DeleteTask удаляет задачу вместе с её связями
+func (b *TaskBatch) DeleteTask(id, ownerID, version int64) error {
+	attachments, err := getAttachments(b.tx, id)
+	if err != nil {
+		return err
+	}
+	if err := deleteTask(b.tx, id, ownerID, version); err != nil {
+		return err
+	}
+	b.attachments = append(b.attachments, attachments...)
+	return nil
+}
+
+// CompleteTask выполняет задачу так же, как CompleteTask вне пакета
+func (b *TaskBatch) CompleteTask(id, ownerID, version int64, force bool, now time.Time) (Task, bool, error) {
+	attachments, err := getAttachments(b.tx, id)
+	if err != nil {
+		return Task{}, false, err
+	}
+	task, deleted, err := completeTask(b.tx, id, ownerID, version, force, now)
+	if err != nil {
+		return Task{}, false, err
+	}
+	if deleted {
+		b.attachments = append(b.attachments, attachments...)
+	}
+	return task, deleted, nil
+}
+
+// Version возвращает текущую версию задачи
+func (b *TaskBatch) Version(id int64) (int64, error) {
+	var version int64
+	err := b.tx.QueryRow(`SELECT version FROM scheduler WHERE id = ?`, id).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrTaskNotFound
+	}
+	return version, err
+}
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrChecklistItemNotFound = errors.New("пункт чек-листа не найден")
+
+// Пункт чек-листа задачи
+type ChecklistItem struct {
+	ID       string `json:"id"`
+	TaskID   string `json:"task_id"`
+	Title    string `json:"title"`
+	Done     bool   `json:"done"`
+	Position int    `json:"position"`
+}
+
+// Прогресс выполнения чек-листа задачи
+type ChecklistProgress struct {
+	Total int `json:"total"`
+	Done  int `json:"done"`
+}
+
+const checklistColumns = `id, task_id, title, done, position`
+
+// Считываем пункт чек-листа из строки результата запроса
+func scanChecklistItem(row scanner) (ChecklistItem, error) {
+	var item ChecklistItem
+	var id, taskID int64
+	if err := row.Scan(&id, &taskID, &item.Title, &item.Done, &item.Position); err != nil {
+		return ChecklistItem{}, err
+	}
+	item.ID = fmt.Sprintf("%d", id)
+	item.TaskID = fmt.Sprintf("%d", taskID)
+	return item, nil
+}
+
+// Возвращаем пункты чек-листа задачи по порядку
+func GetChecklist(taskID int64) ([]ChecklistItem, error) {
+	query := `SELECT ` + checklistColumns + ` FROM checklist_items WHERE task_id = ? ORDER BY position, id`
+	rows, err := DB.Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ChecklistItem
+	for rows.Next() {
+		item, err := scanChecklistItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Возвращаем пункт чек-листа по его идентификатору
+func GetChecklistItem(id int64) (ChecklistItem, error) {
+	item, err := scanChecklistItem(DB.QueryRow(`SELECT `+checklistColumns+` FROM checklist_items WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ChecklistItem{}, ErrChecklistItemNotFound
+	} else if err != nil {
+		return ChecklistItem{}, err
+	}
+	return item, nil
+}
+
+// Добавляем пункт в конец чек-листа задачи и возвращаем его идентификатор.
+// Изменения чек-листа увеличивают версию задачи
+func AddChecklistItem(item ChecklistItem) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO checklist_items (task_id, title, done, position)
+        VALUES (?, ?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM checklist_items WHERE task_id = ?))`
+	res, err := tx.Exec(query, item.TaskID, item.Title, item.Done, item.TaskID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := touchTask(tx, item.TaskID); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// Обновляем текст и отметку о выполнении пункта чек-листа
+func UpdateChecklistItem(item ChecklistItem) error {
+	return changeChecklistItem(item.ID, `UPDATE checklist_items SET title = ?, done = ? WHERE id = ?`, item.Title, item.Done, item.ID)
+}
+
+// Удаляем пункт чек-листа
+func DeleteChecklistItem(id int64) error {
+	return changeChecklistItem(id, `DELETE FROM checklist_items WHERE id = ?`, id)
+}
+
+// Изменяем пункт чек-листа запросом query и увеличиваем версию его задачи
+func changeChecklistItem(id any, query string, args ...any) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var taskID int64
+	err = tx.QueryRow(`SELECT task_id FROM checklist_items WHERE id = ?`, id).Scan(&taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrChecklistItemNotFound
+	} else if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if err := checkChecklistAffected(res); err != nil {
+		return err
+	}
+
+	if err := touchTask(tx, taskID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Расставляем пункты чек-листа задачи в переданном порядке
+func ReorderChecklist(taskID int64, ids []int64) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, id := range ids {
+		res, err := tx.Exec(`UPDATE checklist_items SET position = ? WHERE id = ? AND task_id = ?`, i+1, id, taskID)
+		if err != nil {
+			return err
+		}
+		if err := checkChecklistAffected(res); err != nil {
+			return err
+		}
+	}
+
+	if err := touchTask(tx, taskID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Заполняем прогресс чек-листа у списка задач одним запросом
+func loadChecklistProgress(tasks []Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for i, task := range tasks {
+		index[task.ID] = i
+		args = append(args, task.ID)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(tasks)), ",")
+	query := `SELECT task_id, COUNT(*), COALESCE(SUM(done), 0) FROM checklist_items
+        WHERE task_id IN (` + placeholders + `) GROUP BY task_id`
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int64
+		var progress ChecklistProgress
+		if err := rows.Scan(&taskID, &progress.Total, &progress.Done); err != nil {
+			return err
+		}
+		tasks[index[fmt.Sprintf("%d", taskID)]].Checklist = &progress
+	}
+	return rows.Err()
+}
+
+func checkChecklistAffected(res sql.Result) error {
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrChecklistItemNotFound
+	}
+	return nil
+}
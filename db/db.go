@@ -2,19 +2,42 @@ package db
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
+	"todo-app/utils"
 
-	_ "modernc.org/sqlite"
+	"modernc.org/sqlite"
 )
 
 var DB *sql.DB
 
-// Определение пользовательской ошибки
-var ErrTaskNotFound = errors.New("задача не найдена")
+// Регистрируем SQL-функцию todo_fold для поиска без учёта регистра.
+// Встроенный LIKE в SQLite не различает регистр только для ASCII
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("todo_fold", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch v := args[0].(type) {
+		case string:
+			return utils.FoldText(v), nil
+		case []byte:
+			return utils.FoldText(string(v)), nil
+		default:
+			return "", nil
+		}
+	})
+}
+
+// Определение пользовательских ошибок
+var (
+	ErrTaskNotFound    = errors.New("задача не найдена")
+	ErrVersionConflict = errors.New("задача изменена другим пользователем")
+	ErrTaskBlocked     = errors.New("задача заблокирована невыполненными задачами")
+	ErrInvalidRepeat   = errors.New("некорректное правило повторения")
+)
 
 // Инициализируем базу данных
 func InitDB() {
@@ -36,8 +59,9 @@ func InitDB() {
 		}
 	}
 
-	// Открытие базы данных
-	db, err := sql.Open("sqlite", dbFile)
+	// Открытие базы данных. Транзакции сразу захватывают блокировку записи,
+	// а параллельные запросы ждут её освобождения, а не завершаются ошибкой SQLITE_BUSY
+	db, err := sql.Open("sqlite", dbFile+"?_pragma=busy_timeout(5000)&_txlock=immediate")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -70,12 +94,277 @@ func InitDB() {
 	} else {
 		log.Println("Database already exists.")
 	}
+
+	// Создаём таблицы, появившиеся в новых версиях приложения
+	if err := migrate(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+}
+
+// Дополняем схему базы данных: запросы должны быть идемпотентными,
+// так как выполняются при каждом запуске
+func migrate() error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS users (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            login TEXT NOT NULL UNIQUE CHECK(length(login) <= 64),
+            password_hash TEXT NOT NULL,
+            role TEXT NOT NULL DEFAULT 'member',
+            created_at TEXT NOT NULL
+        );`,
+		`CREATE TABLE IF NOT EXISTS tags (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            owner_id INTEGER,
+            name TEXT NOT NULL CHECK(length(name) <= 64),
+            UNIQUE (owner_id, name)
+        );`,
+		`CREATE TABLE IF NOT EXISTS task_tags (
+            task_id INTEGER NOT NULL,
+            tag_id INTEGER NOT NULL,
+            PRIMARY KEY (task_id, tag_id)
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_task_tags_tag ON task_tags(tag_id);`,
+		`CREATE TABLE IF NOT EXISTS projects (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            name TEXT NOT NULL CHECK(length(name) <= 128),
+            color TEXT NOT NULL DEFAULT '',
+            position INTEGER NOT NULL DEFAULT 0,
+            archived INTEGER NOT NULL DEFAULT 0
+        );`,
+		`CREATE TABLE IF NOT EXISTS checklist_items (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            task_id INTEGER NOT NULL,
+            title TEXT NOT NULL CHECK(length(title) <= 256),
+            done INTEGER NOT NULL DEFAULT 0,
+            position INTEGER NOT NULL DEFAULT 0
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_checklist_task ON checklist_items(task_id);`,
+		`CREATE TABLE IF NOT EXISTS task_dependencies (
+            task_id INTEGER NOT NULL,
+            blocked_by_id INTEGER NOT NULL,
+            PRIMARY KEY (task_id, blocked_by_id)
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_dependencies_blocked_by ON task_dependencies(blocked_by_id);`,
+		`CREATE TABLE IF NOT EXISTS attachments (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            task_id INTEGER NOT NULL,
+            name TEXT NOT NULL,
+            content_type TEXT NOT NULL,
+            size INTEGER NOT NULL,
+            created_at TEXT NOT NULL,
+            storage_key TEXT NOT NULL UNIQUE
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_attachments_task ON attachments(task_id);`,
+		`CREATE TABLE IF NOT EXISTS attachment_blobs (
+            key TEXT PRIMARY KEY,
+            data BLOB NOT NULL
+        );`,
+		`CREATE TABLE IF NOT EXISTS shares (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            owner_id INTEGER NOT NULL,
+            user_id INTEGER NOT NULL,
+            task_id INTEGER,
+            project_id INTEGER,
+            permission TEXT NOT NULL CHECK(permission IN ('viewer', 'editor')),
+            CHECK((task_id IS NULL) != (project_id IS NULL)),
+            UNIQUE (task_id, user_id),
+            UNIQUE (project_id, user_id)
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_shares_user ON shares(user_id);`,
+		`CREATE TABLE IF NOT EXISTS sessions (
+            id TEXT PRIMARY KEY,
+            user_id INTEGER NOT NULL,
+            refresh_hash TEXT NOT NULL,
+            user_agent TEXT NOT NULL DEFAULT '',
+            ip TEXT NOT NULL DEFAULT '',
+            created_at TEXT NOT NULL,
+            last_seen TEXT NOT NULL,
+            expires_at TEXT NOT NULL
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);`,
+		`CREATE TABLE IF NOT EXISTS api_tokens (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            user_id INTEGER NOT NULL,
+            name TEXT NOT NULL CHECK(length(name) <= 128),
+            token_hash TEXT NOT NULL UNIQUE,
+            prefix TEXT NOT NULL,
+            scopes TEXT NOT NULL,
+            created_at TEXT NOT NULL,
+            last_used TEXT NOT NULL DEFAULT '',
+            expires_at TEXT NOT NULL DEFAULT ''
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens(user_id);`,
+		`CREATE TABLE IF NOT EXISTS recovery_codes (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            user_id INTEGER NOT NULL,
+            code_hash TEXT NOT NULL,
+            used_at TEXT NOT NULL DEFAULT ''
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_recovery_codes_user ON recovery_codes(user_id);`,
+		`CREATE TABLE IF NOT EXISTS settings (
+            key TEXT PRIMARY KEY,
+            value TEXT NOT NULL
+        );`,
+		`CREATE TABLE IF NOT EXISTS idempotency_keys (
+            user_id INTEGER NOT NULL,
+            key TEXT NOT NULL,
+            fingerprint TEXT NOT NULL,
+            status INTEGER NOT NULL DEFAULT 0,
+            headers TEXT NOT NULL DEFAULT '',
+            body BLOB,
+            created_at TEXT NOT NULL,
+            PRIMARY KEY (user_id, key)
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(created_at);`,
+	}
+	for _, statement := range statements {
+		if _, err := DB.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	// Новые колонки существующих таблиц
+	columns := []struct{ table, column, definition string }{
+		{"scheduler", "project_id", "INTEGER"},
+		{"scheduler", "priority", "INTEGER NOT NULL DEFAULT 0 CHECK(priority BETWEEN 0 AND 4)"},
+		{"scheduler", "owner_id", "INTEGER"},
+		{"scheduler", "version", "INTEGER NOT NULL DEFAULT 1"},
+		{"projects", "owner_id", "INTEGER"},
+		{"users", "totp_secret", "TEXT NOT NULL DEFAULT ''"},
+		{"users", "totp_enabled", "INTEGER NOT NULL DEFAULT 0"},
+		{"users", "totp_last_step", "INTEGER NOT NULL DEFAULT 0"},
+		{"users", "oidc_subject", "TEXT NOT NULL DEFAULT ''"},
+		{"users", "language", "TEXT NOT NULL DEFAULT ''"},
+	}
+	for _, c := range columns {
+		if err := addColumn(c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	// Имена меток уникальны в пределах пользователя, а не всей базы,
+	// поэтому таблицу меток из старых версий пересоздаём
+	hasOwner, err := hasColumn("tags", "owner_id")
+	if err != nil {
+		return err
+	}
+	if !hasOwner {
+		if err := rebuildTags(); err != nil {
+			return err
+		}
+	}
+
+	indexes := []string{
+		`CREATE INDEX IF NOT EXISTS idx_project ON scheduler(project_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_owner ON scheduler(owner_id);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_oidc ON users(oidc_subject) WHERE oidc_subject != '';`,
+	}
+	for _, index := range indexes {
+		if _, err := DB.Exec(index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Проверяем, есть ли в таблице колонка
+func hasColumn(table, column string) (bool, error) {
+	rows, err := DB.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// Добавляем колонку в таблицу, если её ещё нет
+func addColumn(table, column, definition string) error {
+	exists, err := hasColumn(table, column)
+	if err != nil || exists {
+		return err
+	}
+
+	_, err = DB.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err
+}
+
+// Пересоздаём таблицу меток с колонкой владельца, сохраняя идентификаторы
+func rebuildTags() error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`CREATE TABLE tags_new (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            owner_id INTEGER,
+            name TEXT NOT NULL CHECK(length(name) <= 64),
+            UNIQUE (owner_id, name)
+        );`,
+		`INSERT INTO tags_new (id, name) SELECT id, name FROM tags;`,
+		`DROP TABLE tags;`,
+		`ALTER TABLE tags_new RENAME TO tags;`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Передаём пользователю задачи, проекты и метки без владельца,
+// созданные до появления учётных записей
+func AssignOrphans(ownerID int64) error {
+	for _, table := range []string{"scheduler", "projects", "tags"} {
+		if _, err := DB.Exec(`UPDATE `+table+` SET owner_id = ? WHERE owner_id IS NULL`, ownerID); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Добавляем задачу в базу данных и возвращаем идентификатор новой задачи
-func AddTask(date, title, comment, repeat string) (int64, error) {
-	query := `INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?)`
-	res, err := DB.Exec(query, date, title, comment, repeat)
+func AddTask(task Task) (int64, error) {
+	return addTask(DB, task)
+}
+
+// Добавляем задачу вместе с метками в одной транзакции и возвращаем её идентификатор
+func AddTaskWithTags(task Task, tags []string) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := addTask(tx, task)
+	if err != nil {
+		return 0, err
+	}
+	if len(tags) > 0 {
+		if err := setTaskTags(tx, id, task.OwnerID, tags); err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+// Добавляем задачу вне или внутри транзакции
+func addTask(q querier, task Task) (int64, error) {
+	query := `INSERT INTO scheduler (date, title, comment, repeat, project_id, priority, owner_id) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	res, err := q.Exec(query, task.Date, task.Title, task.Comment, task.Repeat, nullableID(task.ProjectID), task.Priority, task.OwnerID)
 	if err != nil {
 		return 0, err
 	}
@@ -88,46 +377,153 @@ func AddTask(date, title, comment, repeat string) (int64, error) {
 
 // Структура задачи
 type Task struct {
-	ID      string `json:"id"`
-	Date    string `json:"date"`
-	Title   string `json:"title"`
-	Comment string `json:"comment"`
-	Repeat  string `json:"repeat"`
+	ID        string             `json:"id"`
+	Date      string             `json:"date"`
+	Title     string             `json:"title"`
+	Comment   string             `json:"comment"`
+	Repeat    string             `json:"repeat"`
+	ProjectID string             `json:"project_id,omitempty"`
+	Priority  int                `json:"priority,omitempty"`
+	Tags      []string           `json:"tags,omitempty"`
+	Checklist *ChecklistProgress `json:"checklist,omitempty"`
+	Blocked   bool               `json:"blocked,omitempty"`
+	BlockedBy []string           `json:"blocked_by,omitempty"`
+	OwnerID   int64              `json:"-"`
+	// Версия увеличивается при каждом изменении задачи и служит её ETag
+	Version int64 `json:"version,string"`
+	// Права на чужую задачу, открытую пользователю; у своих задач не заполняется
+	Permission string `json:"permission,omitempty"`
 }
 
-// Возвращаем список ближайших задач из базы данных
-// В задании этого нет, но если фронтенд будет поддерживать пагинацию, то это пригодится
+// Приоритет задачи: 1 — самый высокий, 4 — самый низкий, 0 — не задан
+const (
+	NoPriority      = 0
+	HighestPriority = 1
+	LowestPriority  = 4
+)
 
-func GetTasks(limit, offset int) ([]Task, error) {
-	now := time.Now().Format("20060102")
-	query := `SELECT id, date, title, comment, repeat FROM scheduler WHERE date >= ? ORDER BY date LIMIT ? OFFSET ?`
-	rows, err := DB.Query(query, now, limit, offset)
-	if err != nil {
-		return nil, err
+// Порядок сортировки списка задач
+const (
+	SortByDate     = "date"     // по дате, в пределах даты по приоритету
+	SortByPriority = "priority" // по приоритету, в пределах приоритета по дате
+)
+
+// Колонки задачи в порядке, который ожидает scanTask
+const taskColumns = `id, date, title, comment, repeat, project_id, priority, owner_id, version`
+
+// Общий интерфейс для sql.Row и sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// Общий интерфейс для *sql.DB и *sql.Tx
+type querier interface {
+	queryRower
+	Query(query string, args ...any) (*sql.Rows, error)
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// Считываем задачу из строки результата запроса
+func scanTask(row scanner) (Task, error) {
+	var task Task
+	var id int64
+	var projectID, ownerID sql.NullInt64
+	if err := row.Scan(&id, &task.Date, &task.Title, &task.Comment, &task.Repeat, &projectID, &task.Priority, &ownerID, &task.Version); err != nil {
+		return Task{}, err
 	}
-	defer rows.Close()
+	task.ID = fmt.Sprintf("%d", id)
+	if projectID.Valid {
+		task.ProjectID = fmt.Sprintf("%d", projectID.Int64)
+	}
+	task.OwnerID = ownerID.Int64
+	return task, nil
+}
 
-	var tasks []Task
-	for rows.Next() {
-		var task Task
-		var id int64
-		err := rows.Scan(&id, &task.Date, &task.Title, &task.Comment, &task.Repeat)
-		if err != nil {
-			return nil, err
+// Преобразуем строковый идентификатор в значение для необязательной колонки
+func nullableID(id string) sql.NullString {
+	return sql.NullString{String: id, Valid: id != ""}
+}
+
+// Условия выборки списка задач
+type TaskFilter struct {
+	OwnerID int64 // владелец задач
+
+	Search  string   // слова для поиска в заголовке и комментарии
+	Date    string   // конкретная дата в формате 20060102
+	Tags    []string // метки задачи
+	AllTags bool     // задача должна иметь все метки, а не хотя бы одну
+
+	// Задачи проекта; без проекта возвращаются задачи вне архивных проектов
+	ProjectID      int64
+	WithoutProject bool // только задачи, не входящие ни в один проект
+
+	Sort   string // SortByDate или SortByPriority
+	Limit  int
+	Offset int
+}
+
+// Возвращаем список задач по условиям фильтра.
+// Без поиска и даты возвращаются ближайшие задачи, начиная с сегодняшнего дня
+func ListTasks(filter TaskFilter) ([]Task, error) {
+	conditions := []string{`owner_id = ?`}
+	args := []any{filter.OwnerID}
+
+	switch {
+	case filter.Date != "":
+		conditions = append(conditions, `date = ?`)
+		args = append(args, filter.Date)
+	case filter.Search != "":
+		terms := utils.SearchTerms(filter.Search)
+		if len(terms) == 0 {
+			terms = []string{utils.FoldText(filter.Search)}
 		}
-		task.ID = fmt.Sprintf("%d", id)
-		tasks = append(tasks, task)
+		// Каждое слово запроса должно встречаться в заголовке или комментарии задачи
+		for _, term := range terms {
+			pattern := "%" + escapeLike(term) + "%"
+			conditions = append(conditions, `(todo_fold(title) LIKE ? ESCAPE '\' OR todo_fold(comment) LIKE ? ESCAPE '\')`)
+			args = append(args, pattern, pattern)
+		}
+	default:
+		conditions = append(conditions, `date >= ?`)
+		args = append(args, time.Now().Format("20060102"))
 	}
-	if err = rows.Err(); err != nil {
-		return nil, err
+
+	if len(filter.Tags) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(filter.Tags)), ",")
+		tagQuery := `id IN (SELECT task_tags.task_id FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE tags.owner_id = ? AND tags.name IN (` + placeholders + `)`
+		if filter.AllTags {
+			tagQuery += ` GROUP BY task_tags.task_id HAVING COUNT(DISTINCT tags.id) = ?`
+		}
+		conditions = append(conditions, tagQuery+`)`)
+		args = append(args, filter.OwnerID)
+		for _, tag := range filter.Tags {
+			args = append(args, tag)
+		}
+		if filter.AllTags {
+			args = append(args, len(filter.Tags))
+		}
 	}
-	return tasks, nil
-}
 
-// Возвращаем задачи по заданной дате
-func GetTasksByDate(date string, limit, offset int) ([]Task, error) {
-	query := `SELECT id, date, title, comment, repeat FROM scheduler WHERE date = ? ORDER BY date LIMIT ? OFFSET ?`
-	rows, err := DB.Query(query, date, limit, offset)
+	switch {
+	case filter.ProjectID != 0:
+		conditions = append(conditions, `project_id = ?`)
+		args = append(args, filter.ProjectID)
+	case filter.WithoutProject:
+		conditions = append(conditions, `project_id IS NULL`)
+	default:
+		conditions = append(conditions, `(project_id IS NULL OR project_id NOT IN (SELECT id FROM projects WHERE archived = 1))`)
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	// Задачи без приоритета идут после задач с самым низким приоритетом
+	priorityOrder := fmt.Sprintf(`CASE priority WHEN %d THEN %d ELSE priority END`, NoPriority, LowestPriority+1)
+	order := `date, ` + priorityOrder
+	if filter.Sort == SortByPriority {
+		order = priorityOrder + `, date`
+	}
+
+	query := `SELECT ` + taskColumns + ` FROM scheduler WHERE ` + strings.Join(conditions, " AND ") + ` ORDER BY ` + order + `, id LIMIT ? OFFSET ?`
+	rows, err := DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -135,69 +531,129 @@ func GetTasksByDate(date string, limit, offset int) ([]Task, error) {
 
 	var tasks []Task
 	for rows.Next() {
-		var task Task
-		var id int64
-		err := rows.Scan(&id, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+		task, err := scanTask(rows)
 		if err != nil {
 			return nil, err
 		}
-		task.ID = fmt.Sprintf("%d", id)
 		tasks = append(tasks, task)
 	}
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+	rows.Close()
+
+	if err := loadTaskDetails(tasks, filter.OwnerID); err != nil {
+		return nil, err
+	}
 	return tasks, nil
 }
 
-// Выполняем поиск задач по подстроке в заголовке или комментарии
-func SearchTasks(search string, limit, offset int) ([]Task, error) {
-	searchTerm := "%" + search + "%"
-	query := `SELECT id, date, title, comment, repeat FROM scheduler WHERE title LIKE ? OR comment LIKE ? ORDER BY date LIMIT ? OFFSET ?`
-	rows, err := DB.Query(query, searchTerm, searchTerm, limit, offset)
+// Возвращаем список ближайших задач пользователя из базы данных
+// В задании этого нет, но если фронтенд будет поддерживать пагинацию, то это пригодится
+func GetTasks(ownerID int64, limit, offset int) ([]Task, error) {
+	return ListTasks(TaskFilter{OwnerID: ownerID, Limit: limit, Offset: offset})
+}
+
+// Возвращаем задачи пользователя по заданной дате
+func GetTasksByDate(ownerID int64, date string, limit, offset int) ([]Task, error) {
+	return ListTasks(TaskFilter{OwnerID: ownerID, Date: date, Limit: limit, Offset: offset})
+}
+
+// Выполняем поиск задач пользователя по словам в заголовке или комментарии
+func SearchTasks(ownerID int64, search string, limit, offset int) ([]Task, error) {
+	return ListTasks(TaskFilter{OwnerID: ownerID, Search: search, Limit: limit, Offset: offset})
+}
+
+// Возвращаем идентификаторы всех задач пользователя
+func GetTaskIDsByOwner(ownerID int64) ([]int64, error) {
+	rows, err := DB.Query(`SELECT id FROM scheduler WHERE owner_id = ?`, ownerID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var tasks []Task
+	var ids []int64
 	for rows.Next() {
-		var task Task
 		var id int64
-		err := rows.Scan(&id, &task.Date, &task.Title, &task.Comment, &task.Repeat)
-		if err != nil {
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
-		task.ID = fmt.Sprintf("%d", id)
-		tasks = append(tasks, task)
+		ids = append(ids, id)
 	}
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-	return tasks, nil
+	return ids, rows.Err()
 }
 
-// Возвращаем задачу по её идентификатору
-func GetTaskByID(id int64) (Task, error) {
-	query := `SELECT id, date, title, comment, repeat FROM scheduler WHERE id = ?`
-	row := DB.QueryRow(query, id)
+// Экранируем спецсимволы LIKE, чтобы они искались буквально
+func escapeLike(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(s)
+}
 
-	var task Task
-	var taskID int64
-	err := row.Scan(&taskID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+// Возвращаем задачу, доступную пользователю: собственную или открытую ему владельцем.
+// Недоступная задача считается ненайденной
+func GetTaskByID(id, userID int64) (Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM scheduler WHERE id = ?`
+	task, err := scanTask(DB.QueryRow(query, id))
 	if errors.Is(err, sql.ErrNoRows) {
 		return Task{}, ErrTaskNotFound
 	} else if err != nil {
 		return Task{}, err
 	}
-	task.ID = fmt.Sprintf("%d", taskID)
-	return task, nil
+
+	if task.OwnerID != userID {
+		task.Permission, err = taskPermission(DB, task, userID)
+		if err != nil {
+			return Task{}, err
+		}
+	}
+
+	tasks := []Task{task}
+	if err := loadTaskDetails(tasks, userID); err != nil {
+		return Task{}, err
+	}
+	return tasks[0], nil
+}
+
+// Дополняем задачи данными из связанных таблиц так, как их видит пользователь
+func loadTaskDetails(tasks []Task, userID int64) error {
+	if err := loadTaskTags(DB, tasks); err != nil {
+		return err
+	}
+	if err := loadChecklistProgress(tasks); err != nil {
+		return err
+	}
+	return loadDependencies(tasks, userID)
+}
+
+// Обновляем задачу в базе данных и увеличиваем её версию. Если версия задачи
+// указана, задача обновляется, только пока её версия не изменилась
+func UpdateTask(task Task) error {
+	return updateTask(DB, task)
+}
+
+// Обновляем задачу с идентификатором id и заменяем её метки в одной транзакции
+func UpdateTaskWithTags(id int64, task Task, tags []string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateTask(tx, task); err != nil {
+		return err
+	}
+	if err := setTaskTags(tx, id, task.OwnerID, tags); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// Обновляем задачу в базе данных
-func UpdateTask(id, date, title, comment, repeat string) error {
-	query := `UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ? WHERE id = ?`
-	res, err := DB.Exec(query, date, title, comment, repeat, id)
+// Обновляем задачу вне или внутри транзакции
+func updateTask(q querier, task Task) error {
+	query := `UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ?, project_id = ?, priority = ?, version = version + 1
+        WHERE id = ? AND owner_id = ? AND (? = 0 OR version = ?)`
+	res, err := q.Exec(query, task.Date, task.Title, task.Comment, task.Repeat, nullableID(task.ProjectID), task.Priority,
+		task.ID, task.OwnerID, task.Version, task.Version)
 	if err != nil {
 		return err
 	}
@@ -206,15 +662,47 @@ func UpdateTask(id, date, title, comment, repeat string) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return ErrTaskNotFound
+		return taskChangeError(q, task.ID, task.OwnerID)
 	}
 	return nil
 }
 
-// Удаляем задачу из базы данных
-func DeleteTask(id int64) error {
-	query := `DELETE FROM scheduler WHERE id = ?`
-	res, err := DB.Exec(query, id)
+// Определяем, почему задача не изменилась: её нет или изменилась её версия
+func taskChangeError(q queryRower, id any, ownerID int64) error {
+	var version int64
+	err := q.QueryRow(`SELECT version FROM scheduler WHERE id = ? AND owner_id = ?`, id, ownerID).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTaskNotFound
+	} else if err != nil {
+		return err
+	}
+	return ErrVersionConflict
+}
+
+// Увеличиваем версию задачи, у которой изменились метки, чек-лист или зависимости
+func touchTask(q querier, id any) error {
+	_, err := q.Exec(`UPDATE scheduler SET version = version + 1 WHERE id = ?`, id)
+	return err
+}
+
+// Удаляем задачу пользователя из базы данных вместе с её связями.
+// Если версия указана, задача удаляется, только пока её версия не изменилась
+func DeleteTask(id, ownerID, version int64) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteTask(tx, id, ownerID, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Удаляем задачу и её связи внутри транзакции
+func deleteTask(tx *sql.Tx, id, ownerID, version int64) error {
+	res, err := tx.Exec(`DELETE FROM scheduler WHERE id = ? AND owner_id = ? AND (? = 0 OR version = ?)`, id, ownerID, version, version)
 	if err != nil {
 		return err
 	}
@@ -223,7 +711,92 @@ func DeleteTask(id int64) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return ErrTaskNotFound
+		return taskChangeError(tx, id, ownerID)
 	}
-	return nil
+
+	if _, err := tx.Exec(`DELETE FROM task_tags WHERE task_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM checklist_items WHERE task_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM task_dependencies WHERE task_id = ? OR blocked_by_id = ?`, id, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM shares WHERE task_id = ?`, id); err != nil {
+		return err
+	}
+	// Файлы вложений удаляет вызывающая сторона, так как они лежат вне базы данных
+	_, err = tx.Exec(`DELETE FROM attachments WHERE task_id = ?`, id)
+	return err
+}
+
+// Выполняем задачу пользователя в одной транзакции, чтобы параллельные запросы
+// не выполнили её дважды. Обычная задача удаляется. Повторяющаяся переносится
+// на следующую после now дату, её чек-лист сбрасывается, а зависящие от неё задачи
+// разблокируются, так как текущее выполнение завершено.
+// Если версия указана, задача выполняется, только пока её версия не изменилась;
+// заблокированная задача выполняется только с force.
+// Возвращаем задачу после выполнения и признак того, что она удалена
+func CompleteTask(id, ownerID, version int64, force bool, now time.Time) (Task, bool, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return Task{}, false, err
+	}
+	defer tx.Rollback()
+
+	task, deleted, err := completeTask(tx, id, ownerID, version, force, now)
+	if err != nil {
+		return Task{}, false, err
+	}
+	return task, deleted, tx.Commit()
+}
+
+// Выполняем задачу внутри транзакции
+func completeTask(tx *sql.Tx, id, ownerID, version int64, force bool, now time.Time) (Task, bool, error) {
+	query := `SELECT ` + taskColumns + ` FROM scheduler WHERE id = ? AND owner_id = ?`
+	task, err := scanTask(tx.QueryRow(query, id, ownerID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, false, ErrTaskNotFound
+	} else if err != nil {
+		return Task{}, false, err
+	}
+	if version != 0 && task.Version != version {
+		return Task{}, false, ErrVersionConflict
+	}
+
+	if !force {
+		var blockers int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM task_dependencies WHERE task_id = ?`, id).Scan(&blockers); err != nil {
+			return Task{}, false, err
+		}
+		if blockers > 0 {
+			return Task{}, false, ErrTaskBlocked
+		}
+	}
+
+	if task.Repeat == "" {
+		if err := deleteTask(tx, id, ownerID, 0); err != nil {
+			return Task{}, false, err
+		}
+		return task, true, nil
+	}
+
+	nextDate, err := utils.NextDate(now, task.Date, task.Repeat)
+	if err != nil {
+		return Task{}, false, fmt.Errorf("%w: %w", ErrInvalidRepeat, err)
+	}
+	if _, err := tx.Exec(`UPDATE scheduler SET date = ?, version = version + 1 WHERE id = ?`, nextDate, id); err != nil {
+		return Task{}, false, err
+	}
+	if _, err := tx.Exec(`UPDATE checklist_items SET done = 0 WHERE task_id = ?`, id); err != nil {
+		return Task{}, false, err
+	}
+	if _, err := tx.Exec(`DELETE FROM task_dependencies WHERE blocked_by_id = ?`, id); err != nil {
+		return Task{}, false, err
+	}
+
+	task.Date = nextDate
+	task.Version++
+	return task, false, nil
 }
@@ -0,0 +1,210 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrDependencyCycle    = errors.New("зависимость образует цикл")
+	ErrDependencyNotFound = errors.New("зависимость не найдена")
+)
+
+// Связь «задача заблокирована другой задачей»
+type Dependency struct {
+	TaskID    string `json:"task_id"`
+	BlockedBy string `json:"blocked_by"`
+}
+
+// Граф зависимостей задачи: все задачи, которые её блокируют прямо или косвенно,
+// и все задачи, которые зависят от неё
+type DependencyGraph struct {
+	Nodes []Task       `json:"nodes"`
+	Edges []Dependency `json:"edges"`
+}
+
+// Добавляем зависимость, если она не образует цикл, и увеличиваем версию задачи
+func AddDependency(taskID, blockedByID int64) error {
+	if taskID == blockedByID {
+		return ErrDependencyCycle
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Цикл возникает, если блокирующая задача сама прямо или косвенно ждёт задачу
+	query := `WITH RECURSIVE chain(id) AS (
+            SELECT blocked_by_id FROM task_dependencies WHERE task_id = ?
+            UNION
+            SELECT task_dependencies.blocked_by_id FROM task_dependencies JOIN chain ON task_dependencies.task_id = chain.id
+        )
+        SELECT COUNT(*) FROM chain WHERE id = ?`
+	var cycles int
+	if err := tx.QueryRow(query, blockedByID, taskID).Scan(&cycles); err != nil {
+		return err
+	}
+	if cycles > 0 {
+		return ErrDependencyCycle
+	}
+
+	res, err := tx.Exec(`INSERT OR IGNORE INTO task_dependencies (task_id, blocked_by_id) VALUES (?, ?)`, taskID, blockedByID)
+	if err != nil {
+		return err
+	}
+	// Повторное добавление существующей зависимости задачу не меняет
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected > 0 {
+		if err := touchTask(tx, taskID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Удаляем зависимость и увеличиваем версию задачи
+func RemoveDependency(taskID, blockedByID int64) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM task_dependencies WHERE task_id = ? AND blocked_by_id = ?`, taskID, blockedByID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrDependencyNotFound
+	}
+
+	if err := touchTask(tx, taskID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Возвращаем граф зависимостей задачи, видимый пользователю
+func GetDependencyGraph(taskID, userID int64) (DependencyGraph, error) {
+	// Блокирующие задачи ищем вверх по цепочке, зависящие — вниз
+	query := `WITH RECURSIVE
+        up(task_id, blocked_by_id) AS (
+            SELECT task_id, blocked_by_id FROM task_dependencies WHERE task_id = ?
+            UNION
+            SELECT d.task_id, d.blocked_by_id FROM task_dependencies d JOIN up ON d.task_id = up.blocked_by_id
+        ),
+        down(task_id, blocked_by_id) AS (
+            SELECT task_id, blocked_by_id FROM task_dependencies WHERE blocked_by_id = ?
+            UNION
+            SELECT d.task_id, d.blocked_by_id FROM task_dependencies d JOIN down ON d.blocked_by_id = down.task_id
+        )
+        SELECT task_id, blocked_by_id FROM up
+        UNION
+        SELECT task_id, blocked_by_id FROM down`
+	rows, err := DB.Query(query, taskID, taskID)
+	if err != nil {
+		return DependencyGraph{}, err
+	}
+	defer rows.Close()
+
+	graph := DependencyGraph{Nodes: []Task{}, Edges: []Dependency{}}
+	ids := []int64{taskID}
+	seen := map[int64]bool{taskID: true}
+	for rows.Next() {
+		var from, to int64
+		if err := rows.Scan(&from, &to); err != nil {
+			return DependencyGraph{}, err
+		}
+		graph.Edges = append(graph.Edges, Dependency{
+			TaskID:    fmt.Sprintf("%d", from),
+			BlockedBy: fmt.Sprintf("%d", to),
+		})
+		for _, id := range []int64{from, to} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return DependencyGraph{}, err
+	}
+	rows.Close()
+
+	// Задачи, недоступные пользователю, в граф не попадают
+	hidden := map[string]bool{}
+	for _, id := range ids {
+		task, err := GetTaskByID(id, userID)
+		if errors.Is(err, ErrTaskNotFound) {
+			hidden[fmt.Sprintf("%d", id)] = true
+			continue
+		} else if err != nil {
+			return DependencyGraph{}, err
+		}
+		graph.Nodes = append(graph.Nodes, task)
+	}
+
+	edges := graph.Edges[:0]
+	for _, edge := range graph.Edges {
+		if !hidden[edge.TaskID] && !hidden[edge.BlockedBy] {
+			edges = append(edges, edge)
+		}
+	}
+	graph.Edges = edges
+	return graph, nil
+}
+
+// Заполняем список блокирующих задач у списка задач одним запросом. В список
+// попадают только задачи, доступные пользователю, но признак блокировки
+// учитывает все блокирующие задачи
+func loadDependencies(tasks []Task, userID int64) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for i, task := range tasks {
+		index[task.ID] = i
+		args = append(args, task.ID)
+	}
+
+	// Доступность блокирующей задачи определяем так же, как в taskPermission
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(tasks)), ",")
+	query := `SELECT d.task_id, d.blocked_by_id, COALESCE(
+            blocker.owner_id = ?
+            OR blocker.id IN (SELECT task_id FROM shares WHERE user_id = ?)
+            OR blocker.project_id IN (SELECT project_id FROM shares WHERE user_id = ?), 0
+        ) FROM task_dependencies d JOIN scheduler blocker ON blocker.id = d.blocked_by_id
+        WHERE d.task_id IN (` + placeholders + `) ORDER BY d.blocked_by_id`
+	args = append([]any{userID, userID, userID}, args...)
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, blockedByID int64
+		var visible bool
+		if err := rows.Scan(&taskID, &blockedByID, &visible); err != nil {
+			return err
+		}
+		i := index[fmt.Sprintf("%d", taskID)]
+		tasks[i].Blocked = true
+		if visible {
+			tasks[i].BlockedBy = append(tasks[i].BlockedBy, fmt.Sprintf("%d", blockedByID))
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"time"
+)
+
+// Сохранённый ответ на запрос с ключом идемпотентности. Пока запрос
+// выполняется, статус равен 0, а ответа ещё нет
+type IdempotentResponse struct {
+	Fingerprint string // отпечаток метода, адреса и тела запроса
+	Status      int
+	Headers     string // заголовки ответа в формате JSON
+	Body        []byte
+}
+
+// Начинаем запрос с ключом идемпотентности. Если ключ новый, он занимается
+// за запросом и возвращается started == true. Иначе возвращается сохранённый
+// по ключу ответ или запись о выполняющемся запросе.
+// Записи старше ttl удаляются, а запросы, не завершённые за lockTimeout,
+// считаются прерванными, и ключ можно занять снова
+func StartIdempotentRequest(userID int64, key, fingerprint string, ttl, lockTimeout time.Duration) (IdempotentResponse, bool, error) {
+	now := time.Now()
+	_, err := DB.Exec(`DELETE FROM idempotency_keys WHERE created_at <= ? OR (status = 0 AND created_at <= ?)`,
+		sessionTime(now.Add(-ttl)), sessionTime(now.Add(-lockTimeout)))
+	if err != nil {
+		return IdempotentResponse{}, false, err
+	}
+
+	res, err := DB.Exec(`INSERT INTO idempotency_keys (user_id, key, fingerprint, created_at) VALUES (?, ?, ?, ?)
+        ON CONFLICT (user_id, key) DO NOTHING`, userID, key, fingerprint, sessionTime(now))
+	if err != nil {
+		return IdempotentResponse{}, false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return IdempotentResponse{}, false, err
+	}
+	if rowsAffected == 1 {
+		return IdempotentResponse{Fingerprint: fingerprint}, true, nil
+	}
+
+	var response IdempotentResponse
+	err = DB.QueryRow(`SELECT fingerprint, status, headers, body FROM idempotency_keys WHERE user_id = ? AND key = ?`, userID, key).
+		Scan(&response.Fingerprint, &response.Status, &response.Headers, &response.Body)
+	return response, false, err
+}
+
+// Сохраняем ответ на запрос, чтобы повторять его для запросов с тем же ключом
+func FinishIdempotentRequest(userID int64, key string, response IdempotentResponse) error {
+	_, err := DB.Exec(`UPDATE idempotency_keys SET status = ?, headers = ?, body = ? WHERE user_id = ? AND key = ?`,
+		response.Status, response.Headers, response.Body, userID, key)
+	return err
+}
+
+// Освобождаем ключ запроса, который не удалось выполнить, чтобы его можно было повторить
+func AbandonIdempotentRequest(userID int64, key string) error {
+	_, err := DB.Exec(`DELETE FROM idempotency_keys WHERE user_id = ? AND key = ?`, userID, key)
+	return err
+}
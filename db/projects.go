@@ -0,0 +1,191 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrProjectNotFound = errors.New("проект не найден")
+	ErrProjectArchived = errors.New("проект находится в архиве")
+)
+
+// Структура проекта (списка задач)
+type Project struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	Position int    `json:"position"`
+	Archived bool   `json:"archived"`
+	Tasks    int    `json:"tasks"`
+	OwnerID  int64  `json:"-"`
+	// Права на чужой проект, открытый пользователю; у своих проектов не заполняется
+	Permission string `json:"permission,omitempty"`
+}
+
+const projectColumns = `projects.id, projects.name, projects.color, projects.position, projects.archived,
+        (SELECT COUNT(*) FROM scheduler WHERE scheduler.project_id = projects.id), projects.owner_id`
+
+// Считываем проект из строки результата запроса
+func scanProject(row scanner) (Project, error) {
+	var project Project
+	var id int64
+	var ownerID sql.NullInt64
+	if err := row.Scan(&id, &project.Name, &project.Color, &project.Position, &project.Archived, &project.Tasks, &ownerID); err != nil {
+		return Project{}, err
+	}
+	project.ID = fmt.Sprintf("%d", id)
+	project.OwnerID = ownerID.Int64
+	return project, nil
+}
+
+// Возвращаем проекты пользователя в порядке их расположения
+func GetProjects(ownerID int64, includeArchived bool) ([]Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE owner_id = ?`
+	if !includeArchived {
+		query += ` AND archived = 0`
+	}
+	query += ` ORDER BY position, id`
+
+	rows, err := DB.Query(query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []Project
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// Возвращаем проект, доступный пользователю: собственный или открытый ему владельцем.
+// Недоступный проект считается ненайденным
+func GetProjectByID(id, userID int64) (Project, error) {
+	project, err := scanProject(DB.QueryRow(`SELECT `+projectColumns+` FROM projects WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Project{}, ErrProjectNotFound
+	} else if err != nil {
+		return Project{}, err
+	}
+
+	if project.OwnerID != userID {
+		project.Permission, err = projectPermission(id, userID)
+		if err != nil {
+			return Project{}, err
+		}
+	}
+	return project, nil
+}
+
+// Добавляем проект и возвращаем его идентификатор.
+// Новый проект без указанной позиции встаёт в конец списка
+func AddProject(project Project) (int64, error) {
+	query := `INSERT INTO projects (owner_id, name, color, position)
+        VALUES (?, ?, ?, CASE WHEN ? > 0 THEN ? ELSE (SELECT COALESCE(MAX(position), 0) + 1 FROM projects WHERE owner_id = ?) END)`
+	res, err := DB.Exec(query, project.OwnerID, project.Name, project.Color, project.Position, project.Position, project.OwnerID)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Обновляем название, цвет и позицию проекта
+func UpdateProject(project Project) error {
+	query := `UPDATE projects SET name = ?, color = ?, position = ? WHERE id = ? AND owner_id = ?`
+	res, err := DB.Exec(query, project.Name, project.Color, project.Position, project.ID, project.OwnerID)
+	if err != nil {
+		return err
+	}
+	return checkProjectAffected(res)
+}
+
+// Переносим проект пользователя в архив или возвращаем из архива
+func SetProjectArchived(id, ownerID int64, archived bool) error {
+	res, err := DB.Exec(`UPDATE projects SET archived = ? WHERE id = ? AND owner_id = ?`, archived, id, ownerID)
+	if err != nil {
+		return err
+	}
+	return checkProjectAffected(res)
+}
+
+// Расставляем проекты пользователя в переданном порядке
+func ReorderProjects(ownerID int64, ids []int64) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, id := range ids {
+		res, err := tx.Exec(`UPDATE projects SET position = ? WHERE id = ? AND owner_id = ?`, i+1, id, ownerID)
+		if err != nil {
+			return err
+		}
+		if err := checkProjectAffected(res); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Удаляем проект пользователя. Его задачи остаются, но больше не входят в проект
+func DeleteProject(id, ownerID int64) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM projects WHERE id = ? AND owner_id = ?`, id, ownerID)
+	if err != nil {
+		return err
+	}
+	if err := checkProjectAffected(res); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE scheduler SET project_id = NULL, version = version + 1 WHERE project_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM shares WHERE project_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Переносим задачу пользователя в другой проект; пустой идентификатор убирает задачу из проекта
+func MoveTask(taskID, ownerID int64, projectID string) error {
+	res, err := DB.Exec(`UPDATE scheduler SET project_id = ?, version = version + 1 WHERE id = ? AND owner_id = ?`, nullableID(projectID), taskID, ownerID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+func checkProjectAffected(res sql.Result) error {
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrProjectNotFound
+	}
+	return nil
+}
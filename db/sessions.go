@@ -0,0 +1,138 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("сессия не найдена")
+
+// Сессия пользователя на одном устройстве. Отозванная сессия удаляется,
+// и выданные в ней токены перестают действовать
+type Session struct {
+	ID          string `json:"id"`
+	UserID      int64  `json:"-"`
+	RefreshHash string `json:"-"`
+	UserAgent   string `json:"user_agent"`
+	IP          string `json:"ip"`
+	CreatedAt   string `json:"created_at"`
+	LastSeen    string `json:"last_seen"`
+	ExpiresAt   string `json:"expires_at"`
+	Current     bool   `json:"current"` // сессия, из которой выполнен запрос
+}
+
+const sessionColumns = `id, user_id, refresh_hash, user_agent, ip, created_at, last_seen, expires_at`
+
+// Считываем сессию из строки результата запроса
+func scanSession(row scanner) (Session, error) {
+	var session Session
+	err := row.Scan(&session.ID, &session.UserID, &session.RefreshHash, &session.UserAgent, &session.IP,
+		&session.CreatedAt, &session.LastSeen, &session.ExpiresAt)
+	return session, err
+}
+
+// Текущее время в формате, в котором хранятся сроки сессий
+func sessionTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Добавляем сессию; заодно удаляем истёкшие сессии всех пользователей
+func AddSession(session Session) error {
+	now := sessionTime(time.Now())
+	if _, err := DB.Exec(`DELETE FROM sessions WHERE expires_at <= ?`, now); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO sessions (id, user_id, refresh_hash, user_agent, ip, created_at, last_seen, expires_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := DB.Exec(query, session.ID, session.UserID, session.RefreshHash, session.UserAgent, session.IP,
+		now, now, session.ExpiresAt)
+	return err
+}
+
+// Возвращаем действующую сессию по её идентификатору
+func GetSession(id string) (Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = ? AND expires_at > ?`
+	session, err := scanSession(DB.QueryRow(query, id, sessionTime(time.Now())))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Session{}, ErrSessionNotFound
+	} else if err != nil {
+		return Session{}, err
+	}
+	return session, nil
+}
+
+// Возвращаем действующие сессии пользователя, начиная с последней активной
+func GetSessions(userID int64) ([]Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE user_id = ? AND expires_at > ? ORDER BY last_seen DESC`
+	rows, err := DB.Query(query, userID, sessionTime(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Отмечаем активность в сессии. Чтобы не писать в базу на каждый запрос,
+// время обновляется не чаще раза в минуту
+func TouchSession(id, ip string) error {
+	now := time.Now()
+	_, err := DB.Exec(`UPDATE sessions SET last_seen = ?, ip = ? WHERE id = ? AND last_seen < ?`,
+		sessionTime(now), ip, id, sessionTime(now.Add(-time.Minute)))
+	return err
+}
+
+// Заменяем refresh-токен сессии и продлеваем её срок.
+// Замена выполняется, только если предъявлен текущий refresh-токен
+func RefreshSession(id, oldHash, newHash, expiresAt string) error {
+	query := `UPDATE sessions SET refresh_hash = ?, expires_at = ?, last_seen = ?
+        WHERE id = ? AND refresh_hash = ? AND expires_at > ?`
+	now := sessionTime(time.Now())
+	res, err := DB.Exec(query, newHash, expiresAt, now, id, oldHash, now)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// Отзываем сессию пользователя
+func DeleteSession(id string, userID int64) error {
+	res, err := DB.Exec(`DELETE FROM sessions WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// Закрываем все сессии пользователя
+func DeleteUserSessions(userID int64) error {
+	_, err := DB.Exec(`DELETE FROM sessions WHERE user_id = ?`, userID)
+	return err
+}
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Настройки экземпляра приложения, которые меняет администратор
+const (
+	SettingRequire2FA = "require_2fa" // "true", если всем пользователям нужна двухфакторная аутентификация
+)
+
+// Возвращаем значение настройки или пустую строку, если она не задана
+func GetSetting(key string) (string, error) {
+	var value string
+	err := DB.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+// Сохраняем значение настройки
+func SetSetting(key, value string) error {
+	_, err := DB.Exec(`INSERT INTO settings (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var ErrShareNotFound = errors.New("доступ не найден")
+
+// Уровни доступа к чужой задаче или проекту
+const (
+	PermissionViewer = "viewer" // только просмотр
+	PermissionEditor = "editor" // просмотр, изменение, выполнение и удаление задач
+)
+
+// Доступ пользователя к задаче или проекту владельца.
+// Заполняется ровно одно из полей TaskID и ProjectID
+type Share struct {
+	ID         string `json:"id"`
+	TaskID     string `json:"task_id,omitempty"`
+	ProjectID  string `json:"project_id,omitempty"`
+	UserID     string `json:"user_id"`
+	Login      string `json:"login"`
+	Permission string `json:"permission"`
+	OwnerID    int64  `json:"-"`
+}
+
+const shareColumns = `shares.id, shares.task_id, shares.project_id, shares.user_id, users.login, shares.permission, shares.owner_id`
+
+// Считываем доступ из строки результата запроса
+func scanShare(row scanner) (Share, error) {
+	var share Share
+	var id, userID int64
+	var taskID, projectID sql.NullInt64
+	if err := row.Scan(&id, &taskID, &projectID, &userID, &share.Login, &share.Permission, &share.OwnerID); err != nil {
+		return Share{}, err
+	}
+	share.ID = fmt.Sprintf("%d", id)
+	share.UserID = fmt.Sprintf("%d", userID)
+	if taskID.Valid {
+		share.TaskID = fmt.Sprintf("%d", taskID.Int64)
+	}
+	if projectID.Valid {
+		share.ProjectID = fmt.Sprintf("%d", projectID.Int64)
+	}
+	return share, nil
+}
+
+// Открываем доступ к задаче или проекту и возвращаем идентификатор доступа.
+// Повторный вызов для того же пользователя меняет уровень доступа
+func AddShare(share Share) (int64, error) {
+	target, resourceID := "task_id", share.TaskID
+	if share.ProjectID != "" {
+		target, resourceID = "project_id", share.ProjectID
+	}
+	query := `INSERT INTO shares (owner_id, user_id, task_id, project_id, permission) VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT (` + target + `, user_id) DO UPDATE SET permission = excluded.permission`
+	_, err := DB.Exec(query, share.OwnerID, share.UserID, nullableID(share.TaskID), nullableID(share.ProjectID), share.Permission)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = DB.QueryRow(`SELECT id FROM shares WHERE `+target+` = ? AND user_id = ?`, resourceID, share.UserID).Scan(&id)
+	return id, err
+}
+
+// Возвращаем доступы, выданные владельцем к задаче или проекту
+func GetShares(ownerID int64, taskID, projectID string) ([]Share, error) {
+	query := `SELECT ` + shareColumns + ` FROM shares JOIN users ON users.id = shares.user_id
+        WHERE shares.owner_id = ? AND (shares.task_id = ? OR shares.project_id = ?) ORDER BY shares.id`
+	rows, err := DB.Query(query, ownerID, nullableID(taskID), nullableID(projectID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []Share
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// Закрываем доступ, выданный владельцем
+func DeleteShare(id, ownerID int64) error {
+	res, err := DB.Exec(`DELETE FROM shares WHERE id = ? AND owner_id = ?`, id, ownerID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// Возвращаем чужие задачи, открытые пользователю напрямую или через проект
+func GetSharedTasks(userID int64) ([]Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM scheduler WHERE owner_id != ? AND (
+            id IN (SELECT task_id FROM shares WHERE user_id = ?)
+            OR project_id IN (SELECT project_id FROM shares WHERE user_id = ?)
+        ) ORDER BY date, id`
+	rows, err := DB.Query(query, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range tasks {
+		if tasks[i].Permission, err = taskPermission(DB, tasks[i], userID); err != nil {
+			return nil, err
+		}
+	}
+	if err := loadTaskDetails(tasks, userID); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// Возвращаем чужие проекты, открытые пользователю
+func GetSharedProjects(userID int64) ([]Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects
+        WHERE owner_id != ? AND id IN (SELECT project_id FROM shares WHERE user_id = ?) ORDER BY name, id`
+	rows, err := DB.Query(query, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []Project
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range projects {
+		id, _ := strconv.ParseInt(projects[i].ID, 10, 64)
+		if projects[i].Permission, err = projectPermission(id, userID); err != nil {
+			return nil, err
+		}
+	}
+	return projects, nil
+}
+
+// Определяем права пользователя на чужую задачу: доступ к самой задаче
+// или к её проекту, из двух выбирается более широкий
+func taskPermission(q queryRower, task Task, userID int64) (string, error) {
+	query := `SELECT permission FROM shares WHERE user_id = ? AND (task_id = ? OR project_id = ?)
+        ORDER BY permission = 'editor' DESC LIMIT 1`
+	var permission string
+	err := q.QueryRow(query, userID, task.ID, nullableID(task.ProjectID)).Scan(&permission)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrTaskNotFound
+	}
+	return permission, err
+}
+
+// Определяем права пользователя на чужой проект
+func projectPermission(projectID, userID int64) (string, error) {
+	var permission string
+	err := DB.QueryRow(`SELECT permission FROM shares WHERE user_id = ? AND project_id = ?`, userID, projectID).Scan(&permission)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrProjectNotFound
+	}
+	return permission, err
+}
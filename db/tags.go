@@ -0,0 +1,243 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrTagNotFound = errors.New("метка не найдена")
+	ErrTagExists   = errors.New("метка с таким именем уже существует")
+)
+
+// Структура метки
+type Tag struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Tasks int    `json:"tasks"`
+}
+
+// Возвращаем все метки пользователя с количеством отмеченных ими задач
+func GetTags(ownerID int64) ([]Tag, error) {
+	query := `SELECT tags.id, tags.name, COUNT(task_tags.task_id) FROM tags
+        LEFT JOIN task_tags ON task_tags.tag_id = tags.id
+        WHERE tags.owner_id = ? GROUP BY tags.id ORDER BY tags.name`
+	rows, err := DB.Query(query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []Tag
+	for rows.Next() {
+		var tag Tag
+		var id int64
+		if err := rows.Scan(&id, &tag.Name, &tag.Tasks); err != nil {
+			return nil, err
+		}
+		tag.ID = fmt.Sprintf("%d", id)
+		tags = append(tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// Возвращаем метку пользователя по её идентификатору
+func GetTagByID(id, ownerID int64) (Tag, error) {
+	query := `SELECT tags.id, tags.name, COUNT(task_tags.task_id) FROM tags
+        LEFT JOIN task_tags ON task_tags.tag_id = tags.id
+        WHERE tags.id = ? AND tags.owner_id = ? GROUP BY tags.id`
+	var tag Tag
+	var tagID int64
+	err := DB.QueryRow(query, id, ownerID).Scan(&tagID, &tag.Name, &tag.Tasks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Tag{}, ErrTagNotFound
+	} else if err != nil {
+		return Tag{}, err
+	}
+	tag.ID = fmt.Sprintf("%d", tagID)
+	return tag, nil
+}
+
+// Добавляем метку пользователя и возвращаем её идентификатор
+func AddTag(ownerID int64, name string) (int64, error) {
+	if _, err := findTag(DB, ownerID, name); err == nil {
+		return 0, ErrTagExists
+	} else if !errors.Is(err, ErrTagNotFound) {
+		return 0, err
+	}
+
+	res, err := DB.Exec(`INSERT INTO tags (owner_id, name) VALUES (?, ?)`, ownerID, name)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Переименовываем метку пользователя и увеличиваем версию её задач
+func RenameTag(id, ownerID int64, name string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if existing, err := findTag(tx, ownerID, name); err == nil && existing != id {
+		return ErrTagExists
+	} else if err != nil && !errors.Is(err, ErrTagNotFound) {
+		return err
+	}
+
+	res, err := tx.Exec(`UPDATE tags SET name = ? WHERE id = ? AND owner_id = ?`, name, id, ownerID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrTagNotFound
+	}
+
+	if err := touchTagTasks(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Удаляем метку пользователя, снимаем её со всех задач и увеличиваем их версию
+func DeleteTag(id, ownerID int64) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM tags WHERE id = ? AND owner_id = ?`, id, ownerID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrTagNotFound
+	}
+
+	if err := touchTagTasks(tx, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM task_tags WHERE tag_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Увеличиваем версию задач с меткой: метки входят в задачу, поэтому задача тоже изменилась
+func touchTagTasks(tx *sql.Tx, tagID int64) error {
+	_, err := tx.Exec(`UPDATE scheduler SET version = version + 1 WHERE id IN (SELECT task_id FROM task_tags WHERE tag_id = ?)`, tagID)
+	return err
+}
+
+// Возвращаем метки задачи
+func GetTaskTags(taskID int64) ([]string, error) {
+	query := `SELECT tags.name FROM task_tags JOIN tags ON tags.id = task_tags.tag_id
+        WHERE task_tags.task_id = ? ORDER BY tags.name`
+	rows, err := DB.Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, name)
+	}
+	return tags, rows.Err()
+}
+
+// Заменяем метки задачи внутри транзакции. Отсутствующие метки создаются у владельца задачи
+func setTaskTags(tx *sql.Tx, taskID, ownerID int64, names []string) error {
+	if _, err := tx.Exec(`DELETE FROM task_tags WHERE task_id = ?`, taskID); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		tagID, err := findTag(tx, ownerID, name)
+		if errors.Is(err, ErrTagNotFound) {
+			res, err := tx.Exec(`INSERT INTO tags (owner_id, name) VALUES (?, ?)`, ownerID, name)
+			if err != nil {
+				return err
+			}
+			if tagID, err = res.LastInsertId(); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO task_tags (task_id, tag_id) VALUES (?, ?)`, taskID, tagID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Заполняем метки у списка задач одним запросом
+func loadTaskTags(q querier, tasks []Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for i, task := range tasks {
+		index[task.ID] = i
+		args = append(args, task.ID)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(tasks)), ",")
+	query := `SELECT task_tags.task_id, tags.name FROM task_tags JOIN tags ON tags.id = task_tags.tag_id
+        WHERE task_tags.task_id IN (` + placeholders + `) ORDER BY tags.name`
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int64
+		var name string
+		if err := rows.Scan(&taskID, &name); err != nil {
+			return err
+		}
+		i := index[fmt.Sprintf("%d", taskID)]
+		tasks[i].Tags = append(tasks[i].Tags, name)
+	}
+	return rows.Err()
+}
+
+// Общий интерфейс для запросов вне и внутри транзакции
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// Ищем идентификатор метки пользователя по имени
+func findTag(q queryRower, ownerID int64, name string) (int64, error) {
+	var id int64
+	err := q.QueryRow(`SELECT id FROM tags WHERE owner_id = ? AND name = ?`, ownerID, name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrTagNotFound
+	}
+	return id, err
+}
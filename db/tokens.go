@@ -0,0 +1,107 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrAPITokenNotFound = errors.New("токен не найден")
+
+// Персональный токен доступа для скриптов. Сам токен не хранится, только его хеш
+type APIToken struct {
+	ID        string   `json:"id"`
+	UserID    int64    `json:"-"`
+	Name      string   `json:"name"`
+	Prefix    string   `json:"prefix"` // начало токена, чтобы его можно было узнать в списке
+	Scopes    []string `json:"scopes"`
+	CreatedAt string   `json:"created_at"`
+	LastUsed  string   `json:"last_used,omitempty"`
+	ExpiresAt string   `json:"expires_at,omitempty"`
+	TokenHash string   `json:"-"`
+}
+
+const apiTokenColumns = `id, user_id, name, prefix, scopes, created_at, last_used, expires_at, token_hash`
+
+// Считываем токен из строки результата запроса
+func scanAPIToken(row scanner) (APIToken, error) {
+	var token APIToken
+	var id int64
+	var scopes string
+	err := row.Scan(&id, &token.UserID, &token.Name, &token.Prefix, &scopes, &token.CreatedAt,
+		&token.LastUsed, &token.ExpiresAt, &token.TokenHash)
+	if err != nil {
+		return APIToken{}, err
+	}
+	token.ID = fmt.Sprintf("%d", id)
+	token.Scopes = strings.Fields(scopes)
+	return token, nil
+}
+
+// Добавляем токен и возвращаем его идентификатор
+func AddAPIToken(token APIToken) (int64, error) {
+	query := `INSERT INTO api_tokens (user_id, name, token_hash, prefix, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	res, err := DB.Exec(query, token.UserID, token.Name, token.TokenHash, token.Prefix, strings.Join(token.Scopes, " "),
+		time.Now().UTC().Format(time.RFC3339), token.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Возвращаем токены пользователя
+func GetAPITokens(userID int64) ([]APIToken, error) {
+	rows, err := DB.Query(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Находим действующий токен по хешу и отмечаем его использование
+func UseAPIToken(hash string) (APIToken, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	query := `SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE token_hash = ? AND (expires_at = '' OR expires_at > ?)`
+	token, err := scanAPIToken(DB.QueryRow(query, hash, now))
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIToken{}, ErrAPITokenNotFound
+	} else if err != nil {
+		return APIToken{}, err
+	}
+
+	if _, err := DB.Exec(`UPDATE api_tokens SET last_used = ? WHERE id = ?`, now, token.ID); err != nil {
+		return APIToken{}, err
+	}
+	return token, nil
+}
+
+// Отзываем токен пользователя
+func DeleteAPIToken(id, userID int64) error {
+	res, err := DB.Exec(`DELETE FROM api_tokens WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package db
+
+import "time"
+
+// Сохраняем новый секрет TOTP. Двухфакторная аутентификация включается
+// только после подтверждения кодом, поэтому до этого секрет не действует
+func SetTOTPSecret(userID int64, secret string) error {
+	res, err := DB.Exec(`UPDATE users SET totp_secret = ?, totp_enabled = 0 WHERE id = ?`, secret, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Включаем двухфакторную аутентификацию и заменяем коды восстановления
+func EnableTOTP(userID int64, recoveryHashes []string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE users SET totp_enabled = 1 WHERE id = ? AND totp_secret != ''`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryHashes {
+		if _, err := tx.Exec(`INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Отключаем двухфакторную аутентификацию и удаляем коды восстановления
+func DisableTOTP(userID int64) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE users SET totp_secret = '', totp_enabled = 0, totp_last_step = 0 WHERE id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Отмечаем интервал TOTP использованным. Возвращает false, если код
+// этого или более позднего интервала уже принимался
+func UseTOTPStep(userID, step int64) (bool, error) {
+	res, err := DB.Exec(`UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?`, step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// Погашаем код восстановления. Каждый код действует один раз
+func UseRecoveryCode(userID int64, hash string) (bool, error) {
+	query := `UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at = ''`
+	res, err := DB.Exec(query, time.Now().UTC().Format(time.RFC3339), userID, hash)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// Возвращаем число неиспользованных кодов восстановления
+func CountRecoveryCodes(userID int64) (int, error) {
+	var count int
+	err := DB.QueryRow(`SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at = ''`, userID).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,227 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrUserNotFound    = errors.New("пользователь не найден")
+	ErrUserExists      = errors.New("пользователь с таким логином уже существует")
+	ErrOIDCSubjectUsed = errors.New("учётная запись провайдера уже связана с другим пользователем")
+)
+
+// Роли пользователей
+const (
+	RoleAdmin    = "admin"    // управляет пользователями и настройками
+	RoleMember   = "member"   // работает со своими задачами
+	RoleReadOnly = "readonly" // только просматривает задачи, например на настенном экране
+)
+
+// Структура учётной записи пользователя
+type User struct {
+	ID           string `json:"id"`
+	Login        string `json:"login"`
+	Role         string `json:"role"`
+	CreatedAt    string `json:"created_at"`
+	TOTPEnabled  bool   `json:"totp_enabled"`
+	Language     string `json:"language"`               // язык сообщений API; пустой — по заголовку Accept-Language
+	OIDCSubject  string `json:"oidc_subject,omitempty"` // учётная запись у провайдера OIDC в виде <issuer>|<sub>
+	PasswordHash string `json:"-"`
+	TOTPSecret   string `json:"-"` // в base32; задан и до подтверждения подключения
+	TOTPLastStep int64  `json:"-"` // последний принятый интервал TOTP, чтобы код нельзя было использовать повторно
+}
+
+const userColumns = `id, login, role, created_at, password_hash, totp_secret, totp_enabled, totp_last_step, oidc_subject, language`
+
+// Считываем пользователя из строки результата запроса
+func scanUser(row scanner) (User, error) {
+	var user User
+	var id int64
+	err := row.Scan(&id, &user.Login, &user.Role, &user.CreatedAt, &user.PasswordHash,
+		&user.TOTPSecret, &user.TOTPEnabled, &user.TOTPLastStep, &user.OIDCSubject, &user.Language)
+	if err != nil {
+		return User{}, err
+	}
+	user.ID = fmt.Sprintf("%d", id)
+	return user, nil
+}
+
+// Возвращаем всех пользователей
+func GetUsers() ([]User, error) {
+	rows, err := DB.Query(`SELECT ` + userColumns + ` FROM users ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Возвращаем пользователя по его идентификатору
+func GetUserByID(id int64) (User, error) {
+	return getUser(`id = ?`, id)
+}
+
+// Возвращаем пользователя по логину
+func GetUserByLogin(login string) (User, error) {
+	return getUser(`login = ?`, login)
+}
+
+// Возвращаем пользователя, связанного с учётной записью провайдера OIDC
+func GetUserByOIDCSubject(subject string) (User, error) {
+	return getUser(`oidc_subject = ?`, subject)
+}
+
+func getUser(condition string, arg any) (User, error) {
+	user, err := scanUser(DB.QueryRow(`SELECT `+userColumns+` FROM users WHERE `+condition, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrUserNotFound
+	} else if err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+// Добавляем пользователя и возвращаем его идентификатор
+func AddUser(user User) (int64, error) {
+	if _, err := GetUserByLogin(user.Login); err == nil {
+		return 0, ErrUserExists
+	} else if !errors.Is(err, ErrUserNotFound) {
+		return 0, err
+	}
+
+	query := `INSERT INTO users (login, password_hash, role, created_at) VALUES (?, ?, ?, ?)`
+	res, err := DB.Exec(query, user.Login, user.PasswordHash, user.Role, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Обновляем хеш пароля пользователя
+func SetUserPassword(id int64, passwordHash string) error {
+	res, err := DB.Exec(`UPDATE users SET password_hash = ? WHERE id = ?`, passwordHash, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Обновляем язык сообщений пользователя
+func SetUserLanguage(id int64, language string) error {
+	res, err := DB.Exec(`UPDATE users SET language = ? WHERE id = ?`, language, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Связываем пользователя с учётной записью провайдера OIDC или, если subject пустой,
+// убираем связь. Учётную запись можно связать только с одним пользователем
+func SetOIDCSubject(id int64, subject string) error {
+	if subject != "" {
+		if user, err := GetUserByOIDCSubject(subject); err == nil && user.ID != fmt.Sprintf("%d", id) {
+			return ErrOIDCSubjectUsed
+		} else if err != nil && !errors.Is(err, ErrUserNotFound) {
+			return err
+		}
+	}
+
+	res, err := DB.Exec(`UPDATE users SET oidc_subject = ? WHERE id = ?`, subject, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Меняем роль пользователя
+func SetUserRole(id int64, role string) error {
+	res, err := DB.Exec(`UPDATE users SET role = ? WHERE id = ?`, role, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Удаляем пользователя вместе с его проектами, метками, доступами, сессиями и токенами.
+// Задачи пользователя вызывающая сторона удаляет заранее, так как у них бывают файлы вложений
+func DeleteUser(id int64) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	statements := []string{
+		`DELETE FROM task_tags WHERE tag_id IN (SELECT id FROM tags WHERE owner_id = ?)`,
+		`DELETE FROM tags WHERE owner_id = ?`,
+		`UPDATE scheduler SET project_id = NULL, version = version + 1 WHERE project_id IN (SELECT id FROM projects WHERE owner_id = ?)`,
+		`DELETE FROM projects WHERE owner_id = ?`,
+		`DELETE FROM shares WHERE owner_id = ?`,
+		`DELETE FROM shares WHERE user_id = ?`,
+		`DELETE FROM sessions WHERE user_id = ?`,
+		`DELETE FROM api_tokens WHERE user_id = ?`,
+		`DELETE FROM recovery_codes WHERE user_id = ?`,
+		`DELETE FROM idempotency_keys WHERE user_id = ?`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"todo-app/apierr"
+	"todo-app/auth"
+	"todo-app/db"
+	"todo-app/i18n"
+	"todo-app/storage"
+)
+
+// Максимальный размер вложения по умолчанию — 10 МБ
+const defaultAttachmentMaxSize = 10 << 20
+
+// Определяем максимальный размер вложения из переменной окружения TODO_ATTACHMENT_MAX_SIZE (в байтах)
+func attachmentMaxSize() int64 {
+	if size, err := strconv.ParseInt(os.Getenv("TODO_ATTACHMENT_MAX_SIZE"), 10, 64); err == nil && size > 0 {
+		return size
+	}
+	return defaultAttachmentMaxSize
+}
+
+// Переключаем методы для работы со списком вложений задачи
+func AttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		handleUploadAttachment(w, r)
+	case http.MethodGet:
+		handleGetAttachments(w, r)
+	default:
+		apierr.Write(w, r, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// Переключаем методы для работы с отдельным вложением
+func AttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		handleDownloadAttachment(w, r)
+	case http.MethodDelete:
+		handleDeleteAttachment(w, r)
+	default:
+		apierr.Write(w, r, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// Получаем список вложений задачи
+func handleGetAttachments(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(r.URL.Query().Get("task_id"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор задачи")
+		return
+	}
+
+	if _, err := db.GetTaskByID(taskID, auth.UserID(r)); errors.Is(err, db.ErrTaskNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "задача не найдена")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении задачи")
+		return
+	}
+
+	attachments, err := db.GetAttachments(taskID)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении вложений")
+		return
+	}
+
+	if attachments == nil {
+		attachments = []db.Attachment{}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"attachments": attachments})
+}
+
+// Загружаем вложение из поля file формы multipart/form-data
+func handleUploadAttachment(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(r.URL.Query().Get("task_id"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор задачи")
+		return
+	}
+
+	if _, err := getEditableTask(taskID, auth.UserID(r)); errors.Is(err, db.ErrTaskNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "задача не найдена")
+		return
+	} else if errors.Is(err, errReadOnly) {
+		apierr.Write(w, r, http.StatusForbidden, "недостаточно прав для изменения")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении задачи")
+		return
+	}
+
+	maxSize := attachmentMaxSize()
+	// Запас в 1 МБ оставляем на заголовки и остальные поля формы
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		apierr.Write(w, r, http.StatusBadRequest, "Ожидается форма multipart/form-data")
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			apierr.Write(w, r, http.StatusBadRequest, "Не передан файл")
+			return
+		} else if err != nil {
+			apierr.Write(w, r, http.StatusBadRequest, "Ошибка чтения формы")
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		saveAttachment(w, r, taskID, part.FileName(), part, maxSize)
+		part.Close()
+		return
+	}
+}
+
+// Сохраняем содержимое файла в хранилище, а метаданные — в базу данных
+func saveAttachment(w http.ResponseWriter, r *http.Request, taskID int64, fileName string, file io.Reader, maxSize int64) {
+	name := filepath.Base(filepath.Clean("/" + fileName))
+	if name == "/" || name == "." {
+		name = "file"
+	}
+
+	// Тип содержимого определяем по первым байтам файла, а не по заголовку клиента
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		apierr.Write(w, r, http.StatusBadRequest, "Ошибка чтения файла")
+		return
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+
+	key, err := storage.NewKey()
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при сохранении файла")
+		return
+	}
+
+	content := io.LimitReader(io.MultiReader(bytes.NewReader(head), file), maxSize+1)
+	size, err := storage.Files.Save(key, content)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || (err == nil && size > maxSize) {
+		storage.Files.Delete(key)
+		apierr.Write(w, r, http.StatusRequestEntityTooLarge, i18n.Sprintf(r, "Размер файла превышает %d байт", maxSize))
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при сохранении файла")
+		return
+	}
+
+	id, err := db.AddAttachment(db.Attachment{
+		TaskID:      fmt.Sprintf("%d", taskID),
+		Name:        name,
+		ContentType: contentType,
+		Size:        size,
+		StorageKey:  key,
+	})
+	if err != nil {
+		storage.Files.Delete(key)
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при сохранении вложения")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{"id": fmt.Sprintf("%d", id)})
+}
+
+// Отдаём содержимое вложения
+func handleDownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+
+	attachment, err := getAttachment(id, auth.UserID(r), false)
+	if errors.Is(err, db.ErrAttachmentNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "вложение не найдено")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении вложения")
+		return
+	}
+
+	file, err := storage.Files.Open(attachment.StorageKey)
+	if errors.Is(err, storage.ErrFileNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "файл вложения не найден")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при чтении вложения")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", attachment.Size))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, file)
+}
+
+// Удаляем вложение
+func handleDeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+
+	attachment, err := getAttachment(id, auth.UserID(r), true)
+	if errors.Is(err, db.ErrAttachmentNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "вложение не найдено")
+		return
+	} else if errors.Is(err, errReadOnly) {
+		apierr.Write(w, r, http.StatusForbidden, "недостаточно прав для изменения")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении вложения")
+		return
+	}
+
+	if err := db.DeleteAttachment(id); err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при удалении вложения")
+		return
+	}
+	if err := storage.Files.Delete(attachment.StorageKey); err != nil {
+		log.Printf("Failed to delete attachment file %s: %v", attachment.StorageKey, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{})
+}
+
+// Возвращаем вложение задачи, доступной пользователю; для изменения нужны права на изменение задачи.
+// Вложение недоступной задачи считается ненайденным
+func getAttachment(id, userID int64, edit bool) (db.Attachment, error) {
+	attachment, err := db.GetAttachment(id)
+	if err != nil {
+		return db.Attachment{}, err
+	}
+	taskID, err := strconv.ParseInt(attachment.TaskID, 10, 64)
+	if err != nil {
+		return db.Attachment{}, err
+	}
+	task, err := db.GetTaskByID(taskID, userID)
+	if errors.Is(err, db.ErrTaskNotFound) {
+		return db.Attachment{}, db.ErrAttachmentNotFound
+	} else if err != nil {
+		return db.Attachment{}, err
+	}
+	if edit && task.Permission == db.PermissionViewer {
+		return db.Attachment{}, errReadOnly
+	}
+	return attachment, nil
+}
+
+// Удаляем задачу вместе с файлами её вложений. Версия 0 удаляет задачу без проверки версии
+func deleteTask(id, ownerID, version int64) error {
+	attachments, err := db.GetAttachments(id)
+	if err != nil {
+		return err
+	}
+
+	if err := db.DeleteTask(id, ownerID, version); err != nil {
+		return err
+	}
+	deleteAttachmentFiles(attachments)
+	return nil
+}
+
+// Удаляем файлы вложений удалённой задачи. Задача уже удалена,
+// поэтому ошибки удаления файлов только записываем в лог
+func deleteAttachmentFiles(attachments []db.Attachment) {
+	for _, attachment := range attachments {
+		if err := storage.Files.Delete(attachment.StorageKey); err != nil {
+			log.Printf("Failed to delete attachment file %s: %v", attachment.StorageKey, err)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+	"todo-app/apierr"
+	"todo-app/auth"
+	"todo-app/db"
+	"todo-app/i18n"
+	"todo-app/utils"
+)
+
+// Режимы выполнения пакета операций
+const (
+	batchAtomic     = "atomic"      // всё или ничего: ошибка одной операции отменяет весь пакет
+	batchBestEffort = "best_effort" // ошибка операции отменяет только её изменения
+)
+
+// Операции пакета
+const (
+	batchCreate     = "create"
+	batchUpdate     = "update"
+	batchDelete     = "delete"
+	batchComplete   = "complete"
+	batchReschedule = "reschedule"
+)
+
+// Наибольшее число операций в одном пакете
+const maxBatchOperations = 100
+
+// Операция пакета. Поля task те же, что у тела POST и PUT /api/task
+type batchOperation struct {
+	Op      string `json:"op"`
+	ID      string `json:"id"`
+	Version string `json:"version"`
+	Date    string `json:"date"`
+	Force   bool   `json:"force"`
+	Task    *Task  `json:"task"`
+}
+
+// Результат операции пакета: статус и ошибка те же, что вернул бы отдельный запрос
+type batchResult struct {
+	Op      string        `json:"op"`
+	Status  int           `json:"status"`
+	ID      string        `json:"id,omitempty"`
+	Version string        `json:"version,omitempty"`
+	Error   *apierr.Error `json:"error,omitempty"`
+}
+
+// Проверенная операция пакета, готовая к выполнению
+type batchItem struct {
+	batchOperation
+	id         int64      // задача, к которой относится операция; у create не заполняется
+	version    int64      // версия задачи, которую ожидает клиент; 0 — любая
+	project    db.Project // проект из task.project_id
+	projectErr error      // ошибка проверки проекта; важна, только если проект меняется
+}
+
+// Ошибка операции пакета
+type batchError struct {
+	status int
+	body   apierr.Error
+}
+
+func (e *batchError) Error() string {
+	return e.body.Message
+}
+
+// Ошибка с кодом по умолчанию для статуса
+func newBatchError(r *http.Request, status int, message string) *batchError {
+	return &batchError{status: status, body: apierr.New(r, status, message)}
+}
+
+// Ошибка проверки одного поля операции
+func newBatchFieldError(r *http.Request, field, message string) *batchError {
+	return &batchError{status: http.StatusBadRequest, body: apierr.NewField(r, field, message)}
+}
+
+// Выполняем пакет операций над задачами POST /api/tasks/batch в одной транзакции.
+// В режиме atomic ошибка любой операции отменяет весь пакет, в режиме best_effort —
+// только изменения этой операции. Для каждой операции возвращаем отдельный результат
+func HandleTaskBatch(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Mode       string           `json:"mode"`
+		Operations []batchOperation `json:"operations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	if request.Mode == "" {
+		request.Mode = batchAtomic
+	}
+	if request.Mode != batchAtomic && request.Mode != batchBestEffort {
+		apierr.WriteField(w, r, "mode", "Режим выполнения должен быть atomic или best_effort")
+		return
+	}
+	if len(request.Operations) == 0 {
+		apierr.WriteField(w, r, "operations", "Не указаны операции")
+		return
+	}
+	if len(request.Operations) > maxBatchOperations {
+		apierr.WriteField(w, r, "operations", i18n.Sprintf(r, "В пакете может быть не больше %d операций", maxBatchOperations))
+		return
+	}
+	atomic := request.Mode == batchAtomic
+
+	// Сначала проверяем все операции, чтобы пакет с ошибкой в режиме atomic
+	// отклонялся, не начиная транзакцию
+	results := make([]batchResult, len(request.Operations))
+	items := make([]batchItem, len(request.Operations))
+	failed := false
+	for i, operation := range request.Operations {
+		results[i].Op = operation.Op
+		item, err := prepareBatchItem(r, operation)
+		if err != nil {
+			results[i].fail(r, err)
+			failed = true
+			continue
+		}
+		items[i] = item
+	}
+	if failed && atomic {
+		writeBatchResults(w, false, abortBatch(r, results))
+		return
+	}
+
+	batch, err := db.BeginTaskBatch()
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при выполнении пакета")
+		return
+	}
+	defer batch.Rollback()
+
+	now := time.Now()
+	for i, item := range items {
+		if results[i].Error != nil {
+			continue
+		}
+		err := batch.Savepoint(func() error {
+			var err error
+			results[i], err = executeBatchItem(r, batch, item, now)
+			return err
+		})
+		if err != nil {
+			results[i] = batchResult{Op: item.Op}
+			results[i].fail(r, err)
+			if atomic {
+				batch.Rollback()
+				writeBatchResults(w, false, abortBatch(r, results))
+				return
+			}
+		}
+	}
+
+	if err := batch.Commit(); err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при выполнении пакета")
+		return
+	}
+	deleteAttachmentFiles(batch.DeletedAttachments())
+	writeBatchResults(w, true, results)
+}
+
+// Отправляем результаты пакета. committed сообщает, сохранены ли его изменения
+func writeBatchResults(w http.ResponseWriter, committed bool, results []batchResult) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]any{"committed": committed, "results": results})
+}
+
+// Записываем в результат ошибку операции. Ошибки базы данных не раскрываем
+func (result *batchResult) fail(r *http.Request, err error) {
+	var batchErr *batchError
+	if !errors.As(err, &batchErr) {
+		batchErr = newBatchError(r, http.StatusInternalServerError, "Ошибка при выполнении операции")
+	}
+	result.Status = batchErr.status
+	result.ID = ""
+	result.Version = ""
+	result.Error = &batchErr.body
+}
+
+// Отменяем пакет: операции без собственной ошибки помечаем как отменённые
+func abortBatch(r *http.Request, results []batchResult) []batchResult {
+	for i := range results {
+		if results[i].Error == nil {
+			results[i].fail(r, newBatchError(r, http.StatusFailedDependency, "Операция отменена из-за ошибки в другой операции пакета"))
+		}
+	}
+	return results
+}
+
+// Проверяем операцию, не обращаясь к задачам: задачи читаются уже в транзакции
+func prepareBatchItem(r *http.Request, operation batchOperation) (batchItem, error) {
+	item := batchItem{batchOperation: operation}
+
+	switch operation.Op {
+	case batchCreate, batchUpdate:
+		if operation.Task == nil {
+			return batchItem{}, newBatchFieldError(r, "task", "Не указаны поля задачи")
+		}
+		if field, message := checkTask(r, operation.Task); field != "" {
+			return batchItem{}, newBatchFieldError(r, field, message)
+		}
+		if operation.Task.ProjectID != nil {
+			item.project, item.projectErr = resolveProject(*operation.Task.ProjectID, auth.UserID(r))
+		}
+		// Проект новой задачи проверяется сразу, у изменяемой — только если он меняется
+		if operation.Op == batchCreate {
+			if item.projectErr != nil {
+				return batchItem{}, batchTaskError(r, item.projectErr)
+			}
+			return item, nil
+		}
+	case batchReschedule:
+		if operation.Date == "" {
+			return batchItem{}, newBatchFieldError(r, "date", "Не указана дата")
+		}
+		if _, err := time.Parse("20060102", operation.Date); err != nil {
+			return batchItem{}, newBatchFieldError(r, "date", "Дата указана в неверном формате")
+		}
+	case batchDelete, batchComplete:
+	default:
+		return batchItem{}, newBatchFieldError(r, "op", i18n.Sprintf(r, "Неизвестная операция: %s", operation.Op))
+	}
+
+	if operation.ID == "" {
+		return batchItem{}, &batchError{status: http.StatusBadRequest, body: apierr.NewCode(r, apierr.CodeInvalidID, "Не указан идентификатор задачи")}
+	}
+	id, err := strconv.ParseInt(operation.ID, 10, 64)
+	if err != nil {
+		return batchItem{}, &batchError{status: http.StatusBadRequest, body: apierr.NewCode(r, apierr.CodeInvalidID, "Некорректный идентификатор")}
+	}
+	item.id = id
+
+	switch {
+	case operation.Version != "":
+		if item.version, err = strconv.ParseInt(operation.Version, 10, 64); err != nil || item.version <= 0 {
+			return batchItem{}, newBatchFieldError(r, "version", "Некорректная версия задачи")
+		}
+	case os.Getenv("TODO_REQUIRE_IF_MATCH") == "true":
+		return batchItem{}, newBatchError(r, http.StatusPreconditionRequired, "Не указана версия задачи")
+	}
+	return item, nil
+}
+
+// Выполняем проверенную операцию внутри транзакции пакета
+func executeBatchItem(r *http.Request, batch *db.TaskBatch, item batchItem, now time.Time) (batchResult, error) {
+	result := batchResult{Op: item.Op, Status: http.StatusOK}
+
+	if item.Op == batchCreate {
+		id, err := createBatchTask(r, batch, item)
+		if err != nil {
+			return batchResult{}, err
+		}
+		version, err := batch.Version(id)
+		if err != nil {
+			return batchResult{}, err
+		}
+		result.ID = strconv.FormatInt(id, 10)
+		result.Version = strconv.FormatInt(version, 10)
+		return result, nil
+	}
+
+	current, err := batch.GetTask(item.id, auth.UserID(r))
+	if err == nil && current.Permission == db.PermissionViewer {
+		err = errReadOnly
+	}
+	if err == nil && item.version != 0 && item.version != current.Version {
+		err = db.ErrVersionConflict
+	}
+	if err != nil {
+		return batchResult{}, batchTaskError(r, err)
+	}
+	result.ID = current.ID
+
+	deleted := false
+	switch item.Op {
+	case batchUpdate:
+		err = updateBatchTask(r, batch, item, current)
+	case batchDelete:
+		err = batch.DeleteTask(item.id, current.OwnerID, current.Version)
+		deleted = true
+	case batchComplete:
+		_, deleted, err = batch.CompleteTask(item.id, current.OwnerID, current.Version, item.Force, now)
+	case batchReschedule:
+		// Дата проверяется так же, как при изменении задачи: прошедшая переносится по правилу повторения
+		priority := current.Priority
+		task := Task{Title: current.Title, Date: item.Date, Repeat: current.Repeat, Priority: &priority}
+		if field, message := checkTask(r, &task); field != "" {
+			return batchResult{}, newBatchFieldError(r, field, message)
+		}
+		err = batch.RescheduleTask(item.id, current.OwnerID, current.Version, task.Date)
+	}
+	if err != nil {
+		return batchResult{}, batchTaskError(r, err)
+	}
+
+	if !deleted {
+		version, err := batch.Version(item.id)
+		if err != nil {
+			return batchResult{}, err
+		}
+		result.Version = strconv.FormatInt(version, 10)
+	}
+	return result, nil
+}
+
+// Создаём задачу операции create так же, как POST /api/task
+func createBatchTask(r *http.Request, batch *db.TaskBatch, item batchItem) (int64, error) {
+	task := item.Task
+	tags, err := utils.NormalizeTags(append(task.Tags, utils.ExtractHashtags(task.Title)...))
+	if err != nil {
+		return 0, newBatchFieldError(r, "tags", i18n.Error(r, err))
+	}
+
+	// Задача в открытом пользователю проекте принадлежит владельцу проекта
+	ownerID := auth.UserID(r)
+	if item.project.ID != "" {
+		ownerID = item.project.OwnerID
+	}
+
+	var priority int
+	if task.Priority != nil {
+		priority = *task.Priority
+	}
+
+	return batch.AddTask(db.Task{
+		Date:      task.Date,
+		Title:     task.Title,
+		Comment:   task.Comment,
+		Repeat:    task.Repeat,
+		ProjectID: item.project.ID,
+		Priority:  priority,
+		OwnerID:   ownerID,
+	}, tags)
+}
+
+// Изменяем задачу операции update так же, как PUT /api/task: метки, проект
+// и приоритет, которые не переданы, остаются текущими
+func updateBatchTask(r *http.Request, batch *db.TaskBatch, item batchItem, current db.Task) error {
+	task := *item.Task
+	if task.Tags == nil {
+		task.Tags = current.Tags
+	}
+	priority := current.Priority
+	if task.Priority != nil {
+		priority = *task.Priority
+	}
+	projectID := current.ProjectID
+	if task.ProjectID != nil && *task.ProjectID != projectID {
+		projectID = *task.ProjectID
+		err := item.projectErr
+		if err == nil && item.project.ID != "" && item.project.OwnerID != current.OwnerID {
+			err = errForeignProject
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	tags, err := utils.NormalizeTags(append(task.Tags, utils.ExtractHashtags(task.Title)...))
+	if err != nil {
+		return newBatchFieldError(r, "tags", i18n.Error(r, err))
+	}
+
+	return batch.UpdateTask(item.id, db.Task{
+		ID:        current.ID,
+		Date:      task.Date,
+		Title:     task.Title,
+		Comment:   task.Comment,
+		Repeat:    task.Repeat,
+		ProjectID: projectID,
+		Priority:  priority,
+		OwnerID:   current.OwnerID,
+		Version:   current.Version,
+	}, tags)
+}
+
+// Преобразуем ошибку изменения задачи в ошибку операции с тем же статусом,
+// что вернул бы отдельный запрос
+func batchTaskError(r *http.Request, err error) error {
+	var batchErr *batchError
+	switch {
+	case errors.As(err, &batchErr):
+		return batchErr
+	case errors.Is(err, db.ErrTaskBlocked):
+		return &batchError{status: http.StatusConflict, body: apierr.NewCode(r, apierr.CodeTaskBlocked, "задача заблокирована невыполненными задачами")}
+	case errors.Is(err, db.ErrInvalidRepeat):
+		return newBatchError(r, http.StatusBadRequest, "Ошибка при расчете следующей даты")
+	case errors.Is(err, db.ErrTaskNotFound), errors.Is(err, errReadOnly), errors.Is(err, db.ErrVersionConflict):
+		return newBatchError(r, taskErrorStatus(err), i18n.Error(r, err))
+	}
+	if status := projectErrorStatus(err); status != http.StatusInternalServerError {
+		return newBatchError(r, status, i18n.Error(r, err))
+	}
+	return err
+}
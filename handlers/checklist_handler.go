@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"todo-app/apierr"
+	"todo-app/auth"
+	"todo-app/db"
+)
+
+// Переключаем методы для работы с чек-листом задачи
+func ChecklistHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		handleCreateChecklistItem(w, r)
+	case http.MethodPut:
+		handleUpdateChecklistItem(w, r)
+	case http.MethodGet:
+		handleGetChecklist(w, r)
+	case http.MethodDelete:
+		handleDeleteChecklistItem(w, r)
+	default:
+		apierr.Write(w, r, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// Получаем чек-лист задачи
+func handleGetChecklist(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(r.URL.Query().Get("task_id"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор задачи")
+		return
+	}
+
+	if _, err := db.GetTaskByID(taskID, auth.UserID(r)); errors.Is(err, db.ErrTaskNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "задача не найдена")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении задачи")
+		return
+	}
+
+	items, err := db.GetChecklist(taskID)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении чек-листа")
+		return
+	}
+
+	if items == nil {
+		items = []db.ChecklistItem{}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+// Добавляем пункт в чек-лист задачи
+func handleCreateChecklistItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var item db.ChecklistItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	item.Title = strings.TrimSpace(item.Title)
+	if item.Title == "" {
+		apierr.WriteField(w, r, "title", "Не указан текст пункта")
+		return
+	}
+
+	taskID, err := strconv.ParseInt(item.TaskID, 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор задачи")
+		return
+	}
+
+	if _, err := getEditableTask(taskID, auth.UserID(r)); err != nil {
+		apierr.WriteError(w, r, taskErrorStatus(err), err)
+		return
+	}
+
+	id, err := db.AddChecklistItem(item)
+	if err != nil {
+		apierr.WriteError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]string{"id": fmt.Sprintf("%d", id)}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Обновляем пункт чек-листа: текст и отметку о выполнении
+func handleUpdateChecklistItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var item db.ChecklistItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	id, err := strconv.ParseInt(item.ID, 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+
+	if err := checkChecklistItemAccess(id, auth.UserID(r)); errors.Is(err, db.ErrChecklistItemNotFound) {
+		apierr.WriteError(w, r, http.StatusNotFound, err)
+		return
+	} else if errors.Is(err, errReadOnly) {
+		apierr.WriteError(w, r, http.StatusForbidden, err)
+		return
+	} else if err != nil {
+		apierr.WriteError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	item.Title = strings.TrimSpace(item.Title)
+	if item.Title == "" {
+		apierr.WriteField(w, r, "title", "Не указан текст пункта")
+		return
+	}
+
+	err = db.UpdateChecklistItem(item)
+	if errors.Is(err, db.ErrChecklistItemNotFound) {
+		apierr.WriteError(w, r, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		apierr.WriteError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{})
+}
+
+// Удаляем пункт чек-листа
+func handleDeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+
+	if err := checkChecklistItemAccess(id, auth.UserID(r)); errors.Is(err, db.ErrChecklistItemNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "пункт чек-листа не найден")
+		return
+	} else if errors.Is(err, errReadOnly) {
+		apierr.Write(w, r, http.StatusForbidden, "недостаточно прав для изменения")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении пункта чек-листа")
+		return
+	}
+
+	err = db.DeleteChecklistItem(id)
+	if errors.Is(err, db.ErrChecklistItemNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "пункт чек-листа не найден")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при удалении пункта чек-листа")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{})
+}
+
+// Меняем порядок пунктов чек-листа
+func HandleReorderChecklist(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		TaskID string   `json:"task_id"`
+		IDs    []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	taskID, err := strconv.ParseInt(request.TaskID, 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор задачи")
+		return
+	}
+
+	ids := make([]int64, 0, len(request.IDs))
+	for _, idParam := range request.IDs {
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	if _, err := getEditableTask(taskID, auth.UserID(r)); errors.Is(err, db.ErrTaskNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "задача не найдена")
+		return
+	} else if errors.Is(err, errReadOnly) {
+		apierr.Write(w, r, http.StatusForbidden, "недостаточно прав для изменения")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении задачи")
+		return
+	}
+
+	err = db.ReorderChecklist(taskID, ids)
+	if errors.Is(err, db.ErrChecklistItemNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "пункт чек-листа не найден")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при изменении порядка пунктов")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{})
+}
+
+// Проверяем, что пункт чек-листа относится к задаче, которую пользователь может изменять.
+// Пункт недоступной задачи считается ненайденным
+func checkChecklistItemAccess(id, userID int64) error {
+	item, err := db.GetChecklistItem(id)
+	if err != nil {
+		return err
+	}
+	taskID, err := strconv.ParseInt(item.TaskID, 10, 64)
+	if err != nil {
+		return err
+	}
+	if _, err := getEditableTask(taskID, userID); errors.Is(err, db.ErrTaskNotFound) {
+		return db.ErrChecklistItemNotFound
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
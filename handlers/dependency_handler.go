@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"todo-app/apierr"
+	"todo-app/auth"
+	"todo-app/db"
+)
+
+// Переключаем методы для работы с зависимостями задач
+func DependencyHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		handleAddDependency(w, r)
+	case http.MethodGet:
+		handleGetDependencyGraph(w, r)
+	case http.MethodDelete:
+		handleRemoveDependency(w, r)
+	default:
+		apierr.Write(w, r, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// Добавляем зависимость: задача task_id не может быть выполнена раньше blocked_by
+func handleAddDependency(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var dependency db.Dependency
+	if err := json.NewDecoder(r.Body).Decode(&dependency); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	taskID, err := strconv.ParseInt(dependency.TaskID, 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор задачи")
+		return
+	}
+	blockedByID, err := strconv.ParseInt(dependency.BlockedBy, 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор блокирующей задачи")
+		return
+	}
+
+	// Изменяется зависимая задача, блокирующую достаточно видеть
+	if _, err := getEditableTask(taskID, auth.UserID(r)); err != nil {
+		apierr.WriteError(w, r, taskErrorStatus(err), err)
+		return
+	}
+	if _, err := db.GetTaskByID(blockedByID, auth.UserID(r)); err != nil {
+		apierr.WriteError(w, r, taskErrorStatus(err), err)
+		return
+	}
+
+	err = db.AddDependency(taskID, blockedByID)
+	if errors.Is(err, db.ErrDependencyCycle) {
+		apierr.WriteError(w, r, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		apierr.WriteError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{})
+}
+
+// Удаляем зависимость
+func handleRemoveDependency(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(r.URL.Query().Get("task_id"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор задачи")
+		return
+	}
+	blockedByID, err := strconv.ParseInt(r.URL.Query().Get("blocked_by"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор блокирующей задачи")
+		return
+	}
+
+	if _, err := getEditableTask(taskID, auth.UserID(r)); errors.Is(err, db.ErrTaskNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "зависимость не найдена")
+		return
+	} else if errors.Is(err, errReadOnly) {
+		apierr.Write(w, r, http.StatusForbidden, "недостаточно прав для изменения")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении задачи")
+		return
+	}
+
+	err = db.RemoveDependency(taskID, blockedByID)
+	if errors.Is(err, db.ErrDependencyNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "зависимость не найдена")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при удалении зависимости")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{})
+}
+
+// Получаем граф зависимостей задачи
+func handleGetDependencyGraph(w http.ResponseWriter, r *http.Request) {
+	idParam := r.URL.Query().Get("id")
+	if idParam == "" {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Не указан идентификатор")
+		return
+	}
+
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+
+	if _, err := db.GetTaskByID(id, auth.UserID(r)); errors.Is(err, db.ErrTaskNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "задача не найдена")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении задачи")
+		return
+	}
+
+	graph, err := db.GetDependencyGraph(id, auth.UserID(r))
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении зависимостей")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(graph)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+	"todo-app/apierr"
+	"todo-app/auth"
+	"todo-app/db"
+)
+
+// Заголовок с ключом идемпотентности, который клиент генерирует для каждой операции
+// и повторяет при повторных попытках
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// Сколько хранится ответ по ключу идемпотентности, если не задано TODO_IDEMPOTENCY_TTL
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// Через сколько незавершённый запрос считается прерванным, и его ключ можно занять снова
+const idempotencyLockTimeout = time.Minute
+
+// Заголовки ответа, которые сохраняются и повторяются вместе с телом
+var idempotentHeaders = []string{"Content-Type", "Content-Language", "ETag"}
+
+func idempotencyTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("TODO_IDEMPOTENCY_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return defaultIdempotencyTTL
+}
+
+// Idempotent делает POST-запросы с заголовком Idempotency-Key повторяемыми:
+// первый ответ сохраняется в базе данных, и повторный запрос с тем же ключом
+// получает его, не выполняясь снова. Ответы с ошибкой сервера не сохраняются,
+// чтобы запрос можно было повторить. Ключи действуют в пределах пользователя
+func Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > 255 {
+			apierr.Write(w, r, http.StatusBadRequest, "Ключ идемпотентности длиннее 255 символов")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			apierr.Write(w, r, http.StatusBadRequest, "Ошибка чтения запроса")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Повтор должен совпадать с первым запросом: тот же метод, адрес и тело
+		hash := sha256.New()
+		io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+		hash.Write(body)
+		fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+		userID := auth.UserID(r)
+		saved, started, err := db.StartIdempotentRequest(userID, key, fingerprint, idempotencyTTL(), idempotencyLockTimeout)
+		if err != nil {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при проверке ключа идемпотентности")
+			return
+		}
+		if !started {
+			switch {
+			case saved.Fingerprint != fingerprint:
+				apierr.WriteCode(w, r, http.StatusUnprocessableEntity, apierr.CodeIdempotencyKeyReused, "Ключ идемпотентности использован для другого запроса")
+			case saved.Status == 0:
+				apierr.WriteCode(w, r, http.StatusConflict, apierr.CodeIdempotencyKeyInUse, "Запрос с этим ключом идемпотентности ещё выполняется")
+			default:
+				replayResponse(w, saved)
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+
+		if recorder.status >= http.StatusInternalServerError {
+			err = db.AbandonIdempotentRequest(userID, key)
+		} else {
+			headers := map[string]string{}
+			for _, name := range idempotentHeaders {
+				if value := w.Header().Get(name); value != "" {
+					headers[name] = value
+				}
+			}
+			encoded, _ := json.Marshal(headers)
+			err = db.FinishIdempotentRequest(userID, key, db.IdempotentResponse{
+				Status:  recorder.status,
+				Headers: string(encoded),
+				Body:    recorder.body.Bytes(),
+			})
+		}
+		// Ответ уже отправлен, поэтому ошибку можно только записать в лог
+		if err != nil {
+			log.Printf("Failed to save idempotent response for key %q: %v", key, err)
+		}
+	}
+}
+
+// Повторяем сохранённый ответ. Заголовок Idempotent-Replayed сообщает клиенту,
+// что запрос не выполнялся повторно
+func replayResponse(w http.ResponseWriter, saved db.IdempotentResponse) {
+	var headers map[string]string
+	json.Unmarshal([]byte(saved.Headers), &headers)
+	for name, value := range headers {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(saved.Status)
+	w.Write(saved.Body)
+}
+
+// Пропускает ответ клиенту и запоминает его статус и тело
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"time"
 
+	"todo-app/apierr"
+	"todo-app/i18n"
 	"todo-app/utils"
 )
 
@@ -15,13 +17,13 @@ func NextDateHandler(w http.ResponseWriter, r *http.Request) {
 	const layout = "20060102"
 	now, err := time.Parse(layout, nowStr)
 	if err != nil {
-		http.Error(w, "время не может быть преобразовано в корректную дату", http.StatusBadRequest)
+		apierr.WriteField(w, r, "now", "время не может быть преобразовано в корректную дату")
 		return
 	}
 
 	nextDate, err := utils.NextDate(now, date, repeat)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apierr.WriteField(w, r, "repeat", i18n.Error(r, err))
 		return
 	}
 
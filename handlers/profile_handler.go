@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"todo-app/apierr"
+	"todo-app/auth"
+	"todo-app/db"
+	"todo-app/i18n"
+)
+
+// Переключаем методы для работы с профилем текущего пользователя
+func ProfileHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		json.NewEncoder(w).Encode(auth.CurrentUser(r))
+	case http.MethodPut:
+		handleUpdateProfile(w, r)
+	default:
+		apierr.Write(w, r, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// Меняем язык сообщений пользователя. Пустой язык — выбор по заголовку Accept-Language
+func handleUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Language *string `json:"language"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	user := auth.CurrentUser(r)
+	if request.Language != nil {
+		if *request.Language != "" && !i18n.Supported(*request.Language) {
+			apierr.WriteField(w, r, "language", "Язык не поддерживается")
+			return
+		}
+		if err := db.SetUserLanguage(auth.UserID(r), *request.Language); err != nil {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при сохранении профиля")
+			return
+		}
+		user.Language = *request.Language
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(user)
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"todo-app/apierr"
+	"todo-app/auth"
+	"todo-app/db"
+)
+
+// Цвет проекта задаётся в формате #RRGGBB
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Переключаем методы для работы с проектами
+func ProjectHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		handleCreateProject(w, r)
+	case http.MethodPut:
+		handleUpdateProject(w, r)
+	case http.MethodGet:
+		handleGetProject(w, r)
+	case http.MethodDelete:
+		handleDeleteProject(w, r)
+	default:
+		apierr.Write(w, r, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// Обработчик для получения списка проектов
+func GetProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	projects, err := db.GetProjects(auth.UserID(r), r.URL.Query().Get("archived") == "true")
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении списка проектов")
+		return
+	}
+
+	if projects == nil {
+		projects = []db.Project{}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"projects": projects})
+}
+
+// Проверяем поля проекта
+func validateProject(project *db.Project) error {
+	project.Name = strings.TrimSpace(project.Name)
+	if project.Name == "" {
+		return errors.New("Не указано название проекта")
+	}
+	if project.Color != "" && !colorPattern.MatchString(project.Color) {
+		return errors.New("Цвет проекта указан в неверном формате")
+	}
+	if project.Position < 0 {
+		return errors.New("Позиция проекта не может быть отрицательной")
+	}
+	return nil
+}
+
+// Создаём проект
+func handleCreateProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var project db.Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	if err := validateProject(&project); err != nil {
+		apierr.WriteError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	project.OwnerID = auth.UserID(r)
+	id, err := db.AddProject(project)
+	if err != nil {
+		apierr.WriteError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]string{"id": fmt.Sprintf("%d", id)}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Обновляем проект
+func handleUpdateProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var project db.Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	if _, err := strconv.ParseInt(project.ID, 10, 64); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+
+	if err := validateProject(&project); err != nil {
+		apierr.WriteError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	project.OwnerID = auth.UserID(r)
+	err := db.UpdateProject(project)
+	if errors.Is(err, db.ErrProjectNotFound) {
+		apierr.WriteError(w, r, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		apierr.WriteError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{})
+}
+
+// Получаем проект
+func handleGetProject(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+
+	project, err := db.GetProjectByID(id, auth.UserID(r))
+	if errors.Is(err, db.ErrProjectNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "проект не найден")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении проекта")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(project)
+}
+
+// Удаляем проект
+func handleDeleteProject(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+
+	err = db.DeleteProject(id, auth.UserID(r))
+	if errors.Is(err, db.ErrProjectNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "проект не найден")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при удалении проекта")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{})
+}
+
+// Переносим проект в архив
+func HandleArchiveProject(w http.ResponseWriter, r *http.Request) {
+	setProjectArchived(w, r, true)
+}
+
+// Возвращаем проект из архива
+func HandleUnarchiveProject(w http.ResponseWriter, r *http.Request) {
+	setProjectArchived(w, r, false)
+}
+
+func setProjectArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+
+	err = db.SetProjectArchived(id, auth.UserID(r), archived)
+	if errors.Is(err, db.ErrProjectNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "проект не найден")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при обновлении проекта")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{})
+}
+
+// Меняем порядок проектов
+func HandleReorderProjects(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	ids := make([]int64, 0, len(request.IDs))
+	for _, idParam := range request.IDs {
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	err := db.ReorderProjects(auth.UserID(r), ids)
+	if errors.Is(err, db.ErrProjectNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "проект не найден")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при изменении порядка проектов")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{})
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"todo-app/apierr"
+	"todo-app/db"
+)
+
+// Переключаем методы для управления настройками экземпляра (только для администратора)
+func SettingsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		handleGetSettings(w, r)
+	case http.MethodPut:
+		handleUpdateSettings(w, r)
+	default:
+		apierr.Write(w, r, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// Получаем настройки
+func handleGetSettings(w http.ResponseWriter, r *http.Request) {
+	require2FA, err := db.GetSetting(db.SettingRequire2FA)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении настроек")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"require_2fa": require2FA == "true"})
+}
+
+// Меняем настройки. Поля, которых нет в запросе, остаются прежними
+func handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var request struct {
+		Require2FA *bool `json:"require_2fa"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	if request.Require2FA != nil {
+		value := "false"
+		if *request.Require2FA {
+			value = "true"
+		}
+		if err := db.SetSetting(db.SettingRequire2FA, value); err != nil {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при сохранении настроек")
+			return
+		}
+	}
+
+	handleGetSettings(w, r)
+}
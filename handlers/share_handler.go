@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"todo-app/apierr"
+	"todo-app/auth"
+	"todo-app/db"
+)
+
+// Переключаем методы для управления доступом к задачам и проектам
+func ShareHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		handleCreateShare(w, r)
+	case http.MethodGet:
+		handleGetShares(w, r)
+	case http.MethodDelete:
+		handleDeleteShare(w, r)
+	default:
+		apierr.Write(w, r, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// Обработчик для получения задач и проектов, открытых пользователю другими пользователями
+func GetSharedHandler(w http.ResponseWriter, r *http.Request) {
+	tasks, err := db.GetSharedTasks(auth.UserID(r))
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении списка задач")
+		return
+	}
+	projects, err := db.GetSharedProjects(auth.UserID(r))
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении списка проектов")
+		return
+	}
+
+	if tasks == nil {
+		tasks = []db.Task{}
+	}
+	if projects == nil {
+		projects = []db.Project{}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"tasks": tasks, "projects": projects})
+}
+
+var errShareTarget = errors.New("нужно указать либо задачу, либо проект")
+
+// Проверяем, что открываемая задача или проект принадлежат пользователю.
+// Открывать доступ может только владелец
+func checkShareTarget(taskID, projectID string, userID int64) error {
+	if (taskID == "") == (projectID == "") {
+		return errShareTarget
+	}
+
+	if taskID != "" {
+		id, err := strconv.ParseInt(taskID, 10, 64)
+		if err != nil {
+			return errShareTarget
+		}
+		task, err := db.GetTaskByID(id, userID)
+		if err != nil {
+			return err
+		}
+		if task.Permission != "" {
+			return errReadOnly
+		}
+		return nil
+	}
+
+	id, err := strconv.ParseInt(projectID, 10, 64)
+	if err != nil {
+		return errShareTarget
+	}
+	project, err := db.GetProjectByID(id, userID)
+	if err != nil {
+		return err
+	}
+	if project.Permission != "" {
+		return errReadOnly
+	}
+	return nil
+}
+
+// Определяем код ответа для ошибки проверки открываемой задачи или проекта
+func shareErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errShareTarget):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrTaskNotFound), errors.Is(err, db.ErrProjectNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errReadOnly):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// Открываем доступ к задаче или проекту другому пользователю
+func handleCreateShare(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var request struct {
+		TaskID     string `json:"task_id"`
+		ProjectID  string `json:"project_id"`
+		Login      string `json:"login"`
+		Permission string `json:"permission"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	if request.Permission == "" {
+		request.Permission = db.PermissionViewer
+	}
+	if request.Permission != db.PermissionViewer && request.Permission != db.PermissionEditor {
+		apierr.WriteField(w, r, "permission", "Некорректный уровень доступа")
+		return
+	}
+
+	if err := checkShareTarget(request.TaskID, request.ProjectID, auth.UserID(r)); err != nil {
+		apierr.WriteError(w, r, shareErrorStatus(err), err)
+		return
+	}
+
+	user, err := db.GetUserByLogin(request.Login)
+	if errors.Is(err, db.ErrUserNotFound) {
+		apierr.WriteError(w, r, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		apierr.WriteError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if user.ID == auth.CurrentUser(r).ID {
+		apierr.Write(w, r, http.StatusBadRequest, "Нельзя открыть доступ самому себе")
+		return
+	}
+
+	id, err := db.AddShare(db.Share{
+		TaskID:     request.TaskID,
+		ProjectID:  request.ProjectID,
+		UserID:     user.ID,
+		Permission: request.Permission,
+		OwnerID:    auth.UserID(r),
+	})
+	if err != nil {
+		apierr.WriteError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]string{"id": fmt.Sprintf("%d", id)}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Получаем список пользователей, которым открыта задача или проект
+func handleGetShares(w http.ResponseWriter, r *http.Request) {
+	taskID := r.URL.Query().Get("task_id")
+	projectID := r.URL.Query().Get("project_id")
+	if err := checkShareTarget(taskID, projectID, auth.UserID(r)); err != nil {
+		apierr.WriteError(w, r, shareErrorStatus(err), err)
+		return
+	}
+
+	shares, err := db.GetShares(auth.UserID(r), taskID, projectID)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении списка доступов")
+		return
+	}
+
+	if shares == nil {
+		shares = []db.Share{}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"shares": shares})
+}
+
+// Закрываем доступ
+func handleDeleteShare(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+
+	err = db.DeleteShare(id, auth.UserID(r))
+	if errors.Is(err, db.ErrShareNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "доступ не найден")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при удалении доступа")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{})
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"todo-app/apierr"
+	"todo-app/auth"
+	"todo-app/db"
+	"todo-app/i18n"
+	"todo-app/utils"
+)
+
+// Переключаем методы для работы с метками
+func TagHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		handleCreateTag(w, r)
+	case http.MethodPut:
+		handleUpdateTag(w, r)
+	case http.MethodGet:
+		handleGetTag(w, r)
+	case http.MethodDelete:
+		handleDeleteTag(w, r)
+	default:
+		apierr.Write(w, r, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// Обработчик для получения списка меток
+func GetTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := db.GetTags(auth.UserID(r))
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении списка меток")
+		return
+	}
+
+	if tags == nil {
+		tags = []db.Tag{}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"tags": tags})
+}
+
+// Создаём метку
+func handleCreateTag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var tag db.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	name, err := utils.NormalizeTag(tag.Name)
+	if err != nil {
+		apierr.WriteField(w, r, "name", i18n.Error(r, err))
+		return
+	}
+
+	id, err := db.AddTag(auth.UserID(r), name)
+	if errors.Is(err, db.ErrTagExists) {
+		apierr.WriteError(w, r, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		apierr.WriteError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]string{"id": fmt.Sprintf("%d", id)}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Переименовываем метку
+func handleUpdateTag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var tag db.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	id, err := strconv.ParseInt(tag.ID, 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+
+	name, err := utils.NormalizeTag(tag.Name)
+	if err != nil {
+		apierr.WriteField(w, r, "name", i18n.Error(r, err))
+		return
+	}
+
+	err = db.RenameTag(id, auth.UserID(r), name)
+	if errors.Is(err, db.ErrTagNotFound) {
+		apierr.WriteError(w, r, http.StatusNotFound, err)
+		return
+	} else if errors.Is(err, db.ErrTagExists) {
+		apierr.WriteError(w, r, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		apierr.WriteError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{})
+}
+
+// Получаем метку
+func handleGetTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+
+	tag, err := db.GetTagByID(id, auth.UserID(r))
+	if errors.Is(err, db.ErrTagNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "метка не найдена")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении метки")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(tag)
+}
+
+// Удаляем метку
+func handleDeleteTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+
+	err = db.DeleteTag(id, auth.UserID(r))
+	if errors.Is(err, db.ErrTagNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "метка не найдена")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при удалении метки")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{})
+}
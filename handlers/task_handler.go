@@ -5,19 +5,165 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
+	"todo-app/apierr"
+	"todo-app/auth"
 	"todo-app/db"
+	"todo-app/i18n"
 	"todo-app/utils"
 )
 
 // Структура задачи
 type Task struct {
-	ID      string `db:"id" json:"id"`
-	Date    string `db:"date" json:"date"`
-	Title   string `db:"title" json:"title"`
-	Comment string `db:"comment" json:"comment"`
-	Repeat  string `db:"repeat" json:"repeat"`
+	ID        string   `db:"id" json:"id"`
+	Date      string   `db:"date" json:"date"`
+	Title     string   `db:"title" json:"title"`
+	Comment   string   `db:"comment" json:"comment"`
+	Repeat    string   `db:"repeat" json:"repeat"`
+	ProjectID *string  `db:"project_id" json:"project_id"`
+	Priority  *int     `db:"priority" json:"priority"`
+	Tags      []string `db:"-" json:"tags,omitempty"`
+	Version   string   `db:"version" json:"version,omitempty"`
+}
+
+// Проверяем, что приоритет задачи находится в допустимых пределах
+func checkPriority(priority *int) bool {
+	return priority == nil || *priority == db.NoPriority ||
+		(*priority >= db.HighestPriority && *priority <= db.LowestPriority)
+}
+
+var (
+	errInvalidProject = errors.New("некорректный идентификатор проекта")
+	errForeignProject = errors.New("задачу можно поместить только в проект её владельца")
+	errReadOnly       = errors.New("недостаточно прав для изменения")
+)
+
+// Находим проект, в который пользователь может поместить задачу: свой
+// или открытый ему на редактирование. Пустой идентификатор означает «без проекта»
+func resolveProject(projectID string, userID int64) (db.Project, error) {
+	if projectID == "" {
+		return db.Project{}, nil
+	}
+	id, err := strconv.ParseInt(projectID, 10, 64)
+	if err != nil {
+		return db.Project{}, errInvalidProject
+	}
+	project, err := db.GetProjectByID(id, userID)
+	if err != nil {
+		return db.Project{}, err
+	}
+	if project.Permission == db.PermissionViewer {
+		return db.Project{}, errReadOnly
+	}
+	if project.Archived {
+		return db.Project{}, db.ErrProjectArchived
+	}
+	return project, nil
+}
+
+// Проверяем, что в проект можно поместить задачу указанного владельца
+func checkProject(projectID string, userID, taskOwnerID int64) error {
+	project, err := resolveProject(projectID, userID)
+	if err != nil {
+		return err
+	}
+	if project.ID != "" && project.OwnerID != taskOwnerID {
+		return errForeignProject
+	}
+	return nil
+}
+
+// Определяем код ответа для ошибки проверки проекта
+func projectErrorStatus(err error) int {
+	if errors.Is(err, errReadOnly) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, errInvalidProject) || errors.Is(err, errForeignProject) || errors.Is(err, db.ErrProjectNotFound) || errors.Is(err, db.ErrProjectArchived) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// Загружаем задачу, которую пользователь может изменять: свою или открытую ему на редактирование
+func getEditableTask(id, userID int64) (db.Task, error) {
+	task, err := db.GetTaskByID(id, userID)
+	if err != nil {
+		return db.Task{}, err
+	}
+	if task.Permission == db.PermissionViewer {
+		return db.Task{}, errReadOnly
+	}
+	return task, nil
+}
+
+// Определяем код ответа для ошибки получения задачи
+func taskErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrTaskNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errReadOnly):
+		return http.StatusForbidden
+	case errors.Is(err, db.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusInternalServerError
+}
+
+// ETag задачи — её версия в кавычках
+func taskETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// Проверяем, что клиент изменяет ту версию задачи, которую получил: версия передаётся
+// в заголовке If-Match или полем version тела запроса. Без версии задача изменяется
+// как есть, если не задано TODO_REQUIRE_IF_MATCH=true. При ошибке отправляем ответ и возвращаем false
+func checkTaskVersion(w http.ResponseWriter, r *http.Request, task db.Task, version string) bool {
+	ifMatch := r.Header.Values("If-Match")
+	switch {
+	case len(ifMatch) > 0:
+		if etagMatches(ifMatch, taskETag(task.Version)) {
+			return true
+		}
+	case version != "":
+		if version == strconv.FormatInt(task.Version, 10) {
+			return true
+		}
+	case os.Getenv("TODO_REQUIRE_IF_MATCH") == "true":
+		apierr.Write(w, r, http.StatusPreconditionRequired, "Не указана версия задачи")
+		return false
+	default:
+		return true
+	}
+
+	// Текущая версия помогает клиенту понять, что задачу нужно перечитать
+	w.Header().Set("ETag", taskETag(task.Version))
+	apierr.WriteError(w, r, http.StatusPreconditionFailed, db.ErrVersionConflict)
+	return false
+}
+
+// Определяем код ответа, если задачу изменили параллельно, пока запрос выполнялся:
+// 412, если клиент указал версию задачи, и 409, если не указывал
+func versionConflictStatus(r *http.Request, version string) int {
+	if version != "" || r.Header.Get("If-Match") != "" {
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusConflict
+}
+
+// Ищем ETag в значениях If-Match. Слабые ETag вида W/"1" не совпадают никогда,
+// так как If-Match требует строгого сравнения
+func etagMatches(values []string, etag string) bool {
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag == "*" || tag == etag {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // Переключаем методы
@@ -32,27 +178,30 @@ func TaskHandler(w http.ResponseWriter, r *http.Request) {
 	case http.MethodDelete:
 		handleDeleteTask(w, r)
 	default:
-		http.Error(w, `{"error": "Метод не поддерживается"}`, http.StatusMethodNotAllowed)
+		apierr.Write(w, r, http.StatusMethodNotAllowed, "Метод не поддерживается")
 	}
 }
 
-// Создаём задачу
-func handleCreateTask(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-
-	var task Task
-	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-		response := map[string]string{"error": "Ошибка десериализации JSON"}
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
-		return
+// Проверяем заголовок, приоритет и дату задачи. Пустая дата заменяется сегодняшней,
+// прошедшая — сегодняшней или следующей датой по правилу повторения.
+// При ошибке отправляем ответ и возвращаем false
+func validateTask(w http.ResponseWriter, r *http.Request, task *Task) bool {
+	if field, message := checkTask(r, task); field != "" {
+		apierr.WriteField(w, r, field, message)
+		return false
 	}
+	return true
+}
 
+// Проверяем задачу так же, как validateTask, но не отправляем ответ,
+// а возвращаем поле с ошибкой и сообщение. Без ошибки поле пустое
+func checkTask(r *http.Request, task *Task) (field, message string) {
 	if task.Title == "" {
-		response := map[string]string{"error": "Не указан заголовок задачи"}
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
-		return
+		return "title", "Не указан заголовок задачи"
+	}
+
+	if !checkPriority(task.Priority) {
+		return "priority", "Приоритет задачи должен быть от 1 до 4"
 	}
 
 	const layout = "20060102"
@@ -64,10 +213,7 @@ func handleCreateTask(w http.ResponseWriter, r *http.Request) {
 	} else {
 		parsedDate, err := time.Parse(layout, task.Date)
 		if err != nil {
-			response := map[string]string{"error": "Дата указана в неверном формате"}
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(response)
-			return
+			return "date", "Дата указана в неверном формате"
 		}
 
 		if task.Date != nowStr && parsedDate.Before(now) {
@@ -76,21 +222,67 @@ func handleCreateTask(w http.ResponseWriter, r *http.Request) {
 			} else {
 				nextDate, err := utils.NextDate(now, task.Date, task.Repeat)
 				if err != nil {
-					response := map[string]string{"error": err.Error()}
-					w.WriteHeader(http.StatusBadRequest)
-					json.NewEncoder(w).Encode(response)
-					return
+					return "repeat", i18n.Error(r, err)
 				}
 				task.Date = nextDate
 			}
 		}
 	}
+	return "", ""
+}
+
+// Создаём задачу
+func handleCreateTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var task Task
+	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	if !validateTask(w, r, &task) {
+		return
+	}
+
+	tags, err := utils.NormalizeTags(append(task.Tags, utils.ExtractHashtags(task.Title)...))
+	if err != nil {
+		apierr.WriteField(w, r, "tags", i18n.Error(r, err))
+		return
+	}
 
-	id, err := db.AddTask(task.Date, task.Title, task.Comment, task.Repeat)
+	var projectID string
+	if task.ProjectID != nil {
+		projectID = *task.ProjectID
+	}
+	project, err := resolveProject(projectID, auth.UserID(r))
 	if err != nil {
-		response := map[string]string{"error": err.Error()}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		apierr.WriteError(w, r, projectErrorStatus(err), err)
+		return
+	}
+
+	// Задача в открытом пользователю проекте принадлежит владельцу проекта
+	ownerID := auth.UserID(r)
+	if project.ID != "" {
+		ownerID = project.OwnerID
+	}
+
+	var priority int
+	if task.Priority != nil {
+		priority = *task.Priority
+	}
+
+	id, err := db.AddTaskWithTags(db.Task{
+		Date:      task.Date,
+		Title:     task.Title,
+		Comment:   task.Comment,
+		Repeat:    task.Repeat,
+		ProjectID: projectID,
+		Priority:  priority,
+		OwnerID:   ownerID,
+	}, tags)
+	if err != nil {
+		apierr.WriteError(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -105,93 +297,113 @@ func handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 
 	var task Task
 	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-		response := map[string]string{"error": "Ошибка десериализации JSON"}
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
 		return
 	}
 
 	if task.ID == "" {
-		response := map[string]string{"error": "Не указан идентификатор задачи"}
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Не указан идентификатор задачи")
 		return
 	}
 
-	if task.Title == "" {
-		response := map[string]string{"error": "Не указан заголовок задачи"}
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
+	if !validateTask(w, r, &task) {
 		return
 	}
 
-	const layout = "20060102"
-	now := time.Now()
-	nowStr := now.Format(layout)
-
-	if task.Date == "" {
-		task.Date = nowStr
-	} else {
-		parsedDate, err := time.Parse(layout, task.Date)
-		if err != nil {
-			response := map[string]string{"error": "Дата указана в неверном формате"}
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(response)
-			return
-		}
-
-		if task.Date != nowStr && parsedDate.Before(now) {
-			if task.Repeat == "" {
-				task.Date = nowStr
-			} else {
-				nextDate, err := utils.NextDate(now, task.Date, task.Repeat)
-				if err != nil {
-					response := map[string]string{"error": err.Error()}
-					w.WriteHeader(http.StatusBadRequest)
-					json.NewEncoder(w).Encode(response)
-					return
-				}
-				task.Date = nextDate
-			}
-		}
+	id, err := strconv.ParseInt(task.ID, 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
 	}
 
-	err := db.UpdateTask(task.ID, task.Date, task.Title, task.Comment, task.Repeat)
+	current, err := getEditableTask(id, auth.UserID(r))
 	if err != nil {
-		response := map[string]string{"error": err.Error()}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		apierr.WriteError(w, r, taskErrorStatus(err), err)
 		return
 	}
 
+	if !checkTaskVersion(w, r, current, task.Version) || !storeTask(w, r, id, task, current) {
+		return
+	}
+
+	w.Header().Set("ETag", taskETag(current.Version+1))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{})
 }
 
+// Сохраняем проверенную задачу поверх текущей. Если метки, проект или приоритет
+// не переданы, остаются текущие значения. Задача сохраняется, только пока её версия
+// совпадает с версией current. При ошибке отправляем ответ и возвращаем false
+func storeTask(w http.ResponseWriter, r *http.Request, id int64, task Task, current db.Task) bool {
+	if task.Tags == nil {
+		task.Tags = current.Tags
+	}
+	priority := current.Priority
+	if task.Priority != nil {
+		priority = *task.Priority
+	}
+	projectID := current.ProjectID
+	if task.ProjectID != nil && *task.ProjectID != projectID {
+		projectID = *task.ProjectID
+		if err := checkProject(projectID, auth.UserID(r), current.OwnerID); err != nil {
+			apierr.WriteError(w, r, projectErrorStatus(err), err)
+			return false
+		}
+	}
+
+	tags, err := utils.NormalizeTags(append(task.Tags, utils.ExtractHashtags(task.Title)...))
+	if err != nil {
+		apierr.WriteField(w, r, "tags", i18n.Error(r, err))
+		return false
+	}
+
+	err = db.UpdateTaskWithTags(id, db.Task{
+		ID:        current.ID,
+		Date:      task.Date,
+		Title:     task.Title,
+		Comment:   task.Comment,
+		Repeat:    task.Repeat,
+		ProjectID: projectID,
+		Priority:  priority,
+		OwnerID:   current.OwnerID,
+		Version:   current.Version,
+	}, tags)
+	if errors.Is(err, db.ErrVersionConflict) {
+		apierr.WriteError(w, r, versionConflictStatus(r, task.Version), err)
+		return false
+	} else if err != nil {
+		apierr.WriteError(w, r, taskErrorStatus(err), err)
+		return false
+	}
+
+	return true
+}
+
 // Получаем задачу
 func handleGetTask(w http.ResponseWriter, r *http.Request) {
 	idParam := r.URL.Query().Get("id")
 	if idParam == "" {
-		http.Error(w, `{"error":"Не указан идентификатор"}`, http.StatusBadRequest)
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Не указан идентификатор")
 		return
 	}
 
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		http.Error(w, `{"error":"Некорректный идентификатор"}`, http.StatusBadRequest)
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
 		return
 	}
 
-	task, err := db.GetTaskByID(id)
+	task, err := db.GetTaskByID(id, auth.UserID(r))
 	if errors.Is(err, db.ErrTaskNotFound) {
-		http.Error(w, `{"error":"задача не найдена"}`, http.StatusNotFound)
+		apierr.Write(w, r, http.StatusNotFound, "задача не найдена")
 		return
 	} else if err != nil {
-		http.Error(w, `{"error":"Ошибка при получении задачи"}`, http.StatusInternalServerError)
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении задачи")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("ETag", taskETag(task.Version))
 	json.NewEncoder(w).Encode(task)
 }
 
@@ -199,22 +411,17 @@ func handleGetTask(w http.ResponseWriter, r *http.Request) {
 func handleDeleteTask(w http.ResponseWriter, r *http.Request) {
 	idParam := r.URL.Query().Get("id")
 	if idParam == "" {
-		http.Error(w, `{"error":"Не указан идентификатор"}`, http.StatusBadRequest)
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Не указан идентификатор")
 		return
 	}
 
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		http.Error(w, `{"error":"Некорректный идентификатор"}`, http.StatusBadRequest)
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
 		return
 	}
 
-	err = db.DeleteTask(id)
-	if errors.Is(err, db.ErrTaskNotFound) {
-		http.Error(w, `{"error":"задача не найдена"}`, http.StatusNotFound)
-		return
-	} else if err != nil {
-		http.Error(w, `{"error":"Ошибка при удалении задачи"}`, http.StatusInternalServerError)
+	if !removeTask(w, r, id) {
 		return
 	}
 
@@ -222,50 +429,155 @@ func handleDeleteTask(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{})
 }
 
+// Удаляем задачу, которую пользователь может изменять.
+// При ошибке отправляем ответ и возвращаем false
+func removeTask(w http.ResponseWriter, r *http.Request, id int64) bool {
+	task, err := getEditableTask(id, auth.UserID(r))
+	if errors.Is(err, db.ErrTaskNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "задача не найдена")
+		return false
+	} else if errors.Is(err, errReadOnly) {
+		apierr.Write(w, r, http.StatusForbidden, "недостаточно прав для изменения")
+		return false
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении задачи")
+		return false
+	}
+
+	if !checkTaskVersion(w, r, task, "") {
+		return false
+	}
+
+	err = deleteTask(id, task.OwnerID, task.Version)
+	if errors.Is(err, db.ErrTaskNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "задача не найдена")
+		return false
+	} else if errors.Is(err, db.ErrVersionConflict) {
+		apierr.WriteError(w, r, versionConflictStatus(r, ""), err)
+		return false
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при удалении задачи")
+		return false
+	}
+
+	return true
+}
+
 // Завершаем задачу
 func HandleCompleteTask(w http.ResponseWriter, r *http.Request) {
 	idParam := r.URL.Query().Get("id")
 	if idParam == "" {
-		http.Error(w, `{"error":"Не указан идентификатор"}`, http.StatusBadRequest)
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Не указан идентификатор")
 		return
 	}
 
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		http.Error(w, `{"error":"Некорректный идентификатор"}`, http.StatusBadRequest)
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
 		return
 	}
 
-	task, err := db.GetTaskByID(id)
-	if errors.Is(err, db.ErrTaskNotFound) {
-		http.Error(w, `{"error":"задача не найдена"}`, http.StatusNotFound)
+	task, deleted, ok := completeTask(w, r, id)
+	if !ok {
 		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if !deleted {
+		w.Header().Set("ETag", taskETag(task.Version))
+	}
+	json.NewEncoder(w).Encode(map[string]string{})
+}
+
+// Выполняем задачу: обычная удаляется, повторяющаяся переносится на следующую дату.
+// Выполнение атомарно: из параллельных запросов задачу выполняет только один,
+// остальные получают ошибку. Возвращаем задачу после выполнения и признак того,
+// что она удалена. При ошибке отправляем ответ и возвращаем ok == false
+func completeTask(w http.ResponseWriter, r *http.Request, id int64) (task db.Task, deleted, ok bool) {
+	current, err := getEditableTask(id, auth.UserID(r))
+	if errors.Is(err, db.ErrTaskNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "задача не найдена")
+		return db.Task{}, false, false
+	} else if errors.Is(err, errReadOnly) {
+		apierr.Write(w, r, http.StatusForbidden, "недостаточно прав для изменения")
+		return db.Task{}, false, false
 	} else if err != nil {
-		http.Error(w, `{"error":"Ошибка при получении задачи"}`, http.StatusInternalServerError)
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении задачи")
+		return db.Task{}, false, false
+	}
+
+	if !checkTaskVersion(w, r, current, "") {
+		return db.Task{}, false, false
+	}
+
+	// Вложения запоминаем заранее: если задача будет удалена, нужно удалить и их файлы
+	attachments, err := db.GetAttachments(id)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении вложений")
+		return db.Task{}, false, false
+	}
+
+	// Задача выполняется, только если её не изменили с момента проверки прав.
+	// Заблокированную задачу можно выполнить только явно, с параметром force=true
+	force := r.URL.Query().Get("force") == "true"
+	task, deleted, err = db.CompleteTask(id, current.OwnerID, current.Version, force, time.Now())
+	switch {
+	case errors.Is(err, db.ErrTaskNotFound):
+		apierr.Write(w, r, http.StatusNotFound, "задача не найдена")
+		return db.Task{}, false, false
+	case errors.Is(err, db.ErrTaskBlocked):
+		apierr.WriteCode(w, r, http.StatusConflict, apierr.CodeTaskBlocked, "задача заблокирована невыполненными задачами")
+		return db.Task{}, false, false
+	case errors.Is(err, db.ErrVersionConflict):
+		apierr.WriteError(w, r, versionConflictStatus(r, ""), err)
+		return db.Task{}, false, false
+	case errors.Is(err, db.ErrInvalidRepeat):
+		apierr.Write(w, r, http.StatusBadRequest, "Ошибка при расчете следующей даты")
+		return db.Task{}, false, false
+	case err != nil:
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при обновлении задачи")
+		return db.Task{}, false, false
+	}
+
+	if deleted {
+		deleteAttachmentFiles(attachments)
+	}
+	return task, deleted, true
+}
+
+// Переносим задачу в другой проект
+func HandleMoveTask(w http.ResponseWriter, r *http.Request) {
+	idParam := r.URL.Query().Get("id")
+	if idParam == "" {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Не указан идентификатор")
 		return
 	}
 
-	if task.Repeat == "" {
-		err = db.DeleteTask(id)
-		if errors.Is(err, db.ErrTaskNotFound) {
-			http.Error(w, `{"error":"задача не найдена"}`, http.StatusNotFound)
-			return
-		} else if err != nil {
-			http.Error(w, `{"error":"Ошибка при удалении задачи"}`, http.StatusInternalServerError)
-			return
-		}
-	} else {
-		nextDate, err := utils.NextDate(time.Now(), task.Date, task.Repeat)
-		if err != nil {
-			http.Error(w, `{"error":"Ошибка при расчете следующей даты"}`, http.StatusBadRequest)
-			return
-		}
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
 
-		err = db.UpdateTask(task.ID, nextDate, task.Title, task.Comment, task.Repeat)
-		if err != nil {
-			http.Error(w, `{"error":"Ошибка при обновлении задачи"}`, http.StatusInternalServerError)
-			return
-		}
+	task, err := getEditableTask(id, auth.UserID(r))
+	if err != nil {
+		apierr.WriteError(w, r, taskErrorStatus(err), err)
+		return
+	}
+
+	projectID := r.URL.Query().Get("project_id")
+	if err := checkProject(projectID, auth.UserID(r), task.OwnerID); err != nil {
+		apierr.WriteError(w, r, projectErrorStatus(err), err)
+		return
+	}
+
+	err = db.MoveTask(id, task.OwnerID, projectID)
+	if errors.Is(err, db.ErrTaskNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "задача не найдена")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при переносе задачи")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"todo-app/apierr"
+	"todo-app/auth"
+	"todo-app/db"
+	"todo-app/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// Тип содержимого JSON Merge Patch (RFC 7396)
+const mergePatchType = "application/merge-patch+json"
+
+// Поля задачи, которые можно изменить патчем
+var patchableTaskFields = []string{"date", "title", "comment", "repeat", "project_id", "priority", "tags"}
+
+// Переключаем методы для задачи /api/v2/tasks/{id}
+func TaskV2Handler(w http.ResponseWriter, r *http.Request) {
+	id, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		task, err := db.GetTaskByID(id, auth.UserID(r))
+		if err != nil {
+			apierr.WriteError(w, r, taskErrorStatus(err), err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.Header().Set("ETag", taskETag(task.Version))
+		json.NewEncoder(w).Encode(task)
+	case http.MethodPatch:
+		handlePatchTask(w, r, id)
+	case http.MethodDelete:
+		if removeTask(w, r, id) {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		apierr.Write(w, r, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// Выполняем задачу /api/v2/tasks/{id}/complete. Для повторяющейся задачи
+// возвращаем её со следующей датой, выполненная обычная задача удаляется
+func HandleCompleteTaskV2(w http.ResponseWriter, r *http.Request) {
+	id, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	_, deleted, ok := completeTask(w, r, id)
+	if !ok {
+		return
+	}
+	if deleted {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	task, err := db.GetTaskByID(id, auth.UserID(r))
+	if err != nil {
+		apierr.WriteError(w, r, taskErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("ETag", taskETag(task.Version))
+	json.NewEncoder(w).Encode(task)
+}
+
+// Считываем идентификатор задачи из пути запроса
+func taskIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return 0, false
+	}
+	return id, true
+}
+
+// Частично обновляем задачу. Тело запроса — JSON Merge Patch: переданные поля
+// заменяют текущие, null очищает поле, остальные поля не меняются
+func handlePatchTask(w http.ResponseWriter, r *http.Request, id int64) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != mergePatchType && mediaType != "application/json") {
+			apierr.Write(w, r, http.StatusUnsupportedMediaType, "Ожидается тело application/merge-patch+json")
+			return
+		}
+	}
+
+	var patch map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	for field := range patch {
+		if !slices.Contains(patchableTaskFields, field) {
+			apierr.WriteField(w, r, field, "Поле нельзя изменить")
+			return
+		}
+	}
+
+	current, err := getEditableTask(id, auth.UserID(r))
+	if err != nil {
+		apierr.WriteError(w, r, taskErrorStatus(err), err)
+		return
+	}
+	if !checkTaskVersion(w, r, current, "") {
+		return
+	}
+
+	// Изменяемые поля задачи в том виде, в котором к ним применяется патч.
+	// Пустые проект и приоритет представлены отсутствующими полями
+	document := map[string]any{
+		"date":    current.Date,
+		"title":   current.Title,
+		"comment": current.Comment,
+		"repeat":  current.Repeat,
+		"tags":    current.Tags,
+	}
+	if current.ProjectID != "" {
+		document["project_id"] = current.ProjectID
+	}
+	if current.Priority != db.NoPriority {
+		document["priority"] = current.Priority
+	}
+	if current.Tags == nil {
+		document["tags"] = []string{}
+	}
+
+	merged, err := json.Marshal(utils.MergePatch(document, patch))
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при обновлении задачи")
+		return
+	}
+
+	var task Task
+	if err := json.Unmarshal(merged, &task); err != nil {
+		apierr.Write(w, r, http.StatusBadRequest, "Некорректный тип значения поля")
+		return
+	}
+
+	// Удалённые патчем поля очищаются, а не сохраняют текущие значения
+	if task.Tags == nil {
+		task.Tags = []string{}
+	}
+	if task.ProjectID == nil {
+		task.ProjectID = new(string)
+	}
+	if task.Priority == nil {
+		task.Priority = new(int)
+	}
+
+	if !validateTask(w, r, &task) {
+		return
+	}
+	// Прошедшую дату переносим, только если патч меняет дату или правило повторения,
+	// иначе частичное изменение сдвигало бы просроченную задачу
+	_, hasDate := patch["date"]
+	_, hasRepeat := patch["repeat"]
+	if !hasDate && !hasRepeat {
+		task.Date = current.Date
+	}
+	if !storeTask(w, r, id, task, current) {
+		return
+	}
+
+	updated, err := db.GetTaskByID(id, auth.UserID(r))
+	if err != nil {
+		apierr.WriteError(w, r, taskErrorStatus(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("ETag", taskETag(updated.Version))
+	json.NewEncoder(w).Encode(updated)
+}
@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"todo-app/apierr"
+	"todo-app/auth"
 	"todo-app/db"
+	"todo-app/utils"
 )
 
 // Обработчик для получения списка задач
@@ -36,21 +40,66 @@ func GetTasksHandler(w http.ResponseWriter, r *http.Request) {
 
 	offset := (page - 1) * limit
 
-	var tasks []db.Task
-	var err error
+	filter := db.TaskFilter{OwnerID: auth.UserID(r), Limit: limit, Offset: offset}
 
 	if searchParam != "" {
 		if isDate(searchParam) {
-			tasks, err = db.GetTasksByDate(convertToDate(searchParam), limit, offset)
+			filter.Date = convertToDate(searchParam)
 		} else {
-			tasks, err = db.SearchTasks(searchParam, limit, offset)
+			filter.Search = searchParam
 		}
-	} else {
-		tasks, err = db.GetTasks(limit, offset)
 	}
 
+	// Метки передаются как ?tag=a&tag=b или ?tags=a,b; по умолчанию достаточно любой из них
+	tagParams := r.URL.Query()["tag"]
+	for _, tagsParam := range r.URL.Query()["tags"] {
+		tagParams = append(tagParams, strings.Split(tagsParam, ",")...)
+	}
+	if len(tagParams) > 0 {
+		tags, err := utils.NormalizeTags(tagParams)
+		if err != nil {
+			apierr.WriteField(w, r, "tag", "Некорректная метка")
+			return
+		}
+		filter.Tags = tags
+	}
+
+	switch r.URL.Query().Get("tags_mode") {
+	case "", "any":
+	case "all":
+		filter.AllTags = true
+	default:
+		apierr.WriteField(w, r, "tags_mode", "Режим фильтра по меткам должен быть any или all")
+		return
+	}
+
+	// Задачи одного проекта; project_id=none выбирает задачи вне проектов
+	switch projectParam := r.URL.Query().Get("project_id"); projectParam {
+	case "":
+	case "none":
+		filter.WithoutProject = true
+	default:
+		projectID, err := strconv.ParseInt(projectParam, 10, 64)
+		if err != nil {
+			apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор проекта")
+			return
+		}
+		filter.ProjectID = projectID
+	}
+
+	switch sortParam := r.URL.Query().Get("sort"); sortParam {
+	case "", db.SortByDate:
+		filter.Sort = db.SortByDate
+	case db.SortByPriority:
+		filter.Sort = db.SortByPriority
+	default:
+		apierr.WriteField(w, r, "sort", "Сортировка должна быть date или priority")
+		return
+	}
+
+	tasks, err := db.ListTasks(filter)
 	if err != nil {
-		http.Error(w, `{"error":"Ошибка при получении списка задач"}`, http.StatusInternalServerError)
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении списка задач")
 		return
 	}
 
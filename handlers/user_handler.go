@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"todo-app/apierr"
+	"todo-app/auth"
+	"todo-app/db"
+	"todo-app/i18n"
+	"unicode/utf8"
+)
+
+// Минимальная длина пароля пользователя
+const minPasswordLength = 8
+
+// Проверяем, что роль пользователя известна
+func validRole(role string) bool {
+	return role == db.RoleAdmin || role == db.RoleMember || role == db.RoleReadOnly
+}
+
+// Переключаем методы для управления пользователями (только для администратора)
+func UsersHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		handleCreateUser(w, r)
+	case http.MethodGet:
+		handleGetUsers(w, r)
+	case http.MethodPut:
+		handleUpdateUser(w, r)
+	case http.MethodDelete:
+		handleDeleteUser(w, r)
+	default:
+		apierr.Write(w, r, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// Получаем список пользователей
+func handleGetUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := db.GetUsers()
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении пользователей")
+		return
+	}
+
+	if users == nil {
+		users = []db.User{}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"users": users})
+}
+
+// Регистрируем нового пользователя
+func handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var request struct {
+		Login    string `json:"login"`
+		Password string `json:"password"`
+		Role     string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	request.Login = strings.TrimSpace(request.Login)
+	if request.Login == "" || utf8.RuneCountInString(request.Login) > 64 || strings.ContainsAny(request.Login, " \t\n") {
+		apierr.WriteField(w, r, "login", "Некорректный логин")
+		return
+	}
+
+	if utf8.RuneCountInString(request.Password) < minPasswordLength {
+		apierr.WriteField(w, r, "password", i18n.Sprintf(r, "Пароль должен содержать не менее %d символов", minPasswordLength))
+		return
+	}
+
+	if request.Role == "" {
+		request.Role = db.RoleMember
+	}
+	if !validRole(request.Role) {
+		apierr.WriteField(w, r, "role", "Некорректная роль")
+		return
+	}
+
+	hash, err := auth.HashPassword(request.Password)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при сохранении пароля")
+		return
+	}
+
+	id, err := db.AddUser(db.User{Login: request.Login, Role: request.Role, PasswordHash: hash})
+	if errors.Is(err, db.ErrUserExists) {
+		apierr.WriteError(w, r, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		apierr.WriteError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]string{"id": fmt.Sprintf("%d", id)}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Меняем роль пользователя, задаём ему новый пароль или связываем его с учётной
+// записью провайдера OIDC. После смены пароля все сессии пользователя закрываются
+func handleUpdateUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var request struct {
+		ID          string  `json:"id"`
+		Role        string  `json:"role"`
+		Password    string  `json:"password"`
+		OIDCSubject *string `json:"oidc_subject"` // <issuer>|<sub>; пустая строка убирает связь
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+		return
+	}
+
+	id, err := strconv.ParseInt(request.ID, 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+	user, err := db.GetUserByID(id)
+	if errors.Is(err, db.ErrUserNotFound) {
+		apierr.WriteError(w, r, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		apierr.WriteError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if request.Role != "" && request.Role != user.Role {
+		if !validRole(request.Role) {
+			apierr.WriteField(w, r, "role", "Некорректная роль")
+			return
+		}
+		if auth.IsBuiltinAdmin(user) {
+			apierr.Write(w, r, http.StatusForbidden, "Нельзя изменить роль встроенного администратора")
+			return
+		}
+		if err := db.SetUserRole(id, request.Role); err != nil {
+			apierr.WriteError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	if request.Password != "" {
+		if utf8.RuneCountInString(request.Password) < minPasswordLength {
+			apierr.WriteField(w, r, "password", i18n.Sprintf(r, "Пароль должен содержать не менее %d символов", minPasswordLength))
+			return
+		}
+		hash, err := auth.HashPassword(request.Password)
+		if err != nil {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при сохранении пароля")
+			return
+		}
+		if err := db.SetUserPassword(id, hash); err != nil {
+			apierr.WriteError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		if err := db.DeleteUserSessions(id); err != nil {
+			apierr.WriteError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	if request.OIDCSubject != nil {
+		subject := strings.TrimSpace(*request.OIDCSubject)
+		if subject != "" && (!strings.Contains(subject, "|") || utf8.RuneCountInString(subject) > 512) {
+			apierr.WriteField(w, r, "oidc_subject", "Учётная запись провайдера указывается в виде <issuer>|<sub>")
+			return
+		}
+		err := db.SetOIDCSubject(id, subject)
+		if errors.Is(err, db.ErrOIDCSubjectUsed) {
+			apierr.WriteError(w, r, http.StatusConflict, err)
+			return
+		} else if err != nil {
+			apierr.WriteError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{})
+}
+
+// Удаляем пользователя вместе со всеми его данными
+func handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidID, "Некорректный идентификатор")
+		return
+	}
+
+	user, err := db.GetUserByID(id)
+	if errors.Is(err, db.ErrUserNotFound) {
+		apierr.Write(w, r, http.StatusNotFound, "пользователь не найден")
+		return
+	} else if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении пользователя")
+		return
+	}
+	if auth.IsBuiltinAdmin(user) || id == auth.UserID(r) {
+		apierr.Write(w, r, http.StatusForbidden, "Нельзя удалить встроенного администратора или самого себя")
+		return
+	}
+
+	taskIDs, err := db.GetTaskIDsByOwner(id)
+	if err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при получении задач пользователя")
+		return
+	}
+	for _, taskID := range taskIDs {
+		if err := deleteTask(taskID, id, 0); err != nil && !errors.Is(err, db.ErrTaskNotFound) {
+			apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при удалении задач пользователя")
+			return
+		}
+	}
+
+	if err := db.DeleteUser(id); err != nil {
+		apierr.Write(w, r, http.StatusInternalServerError, "Ошибка при удалении пользователя")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]string{})
+}
@@ -0,0 +1,215 @@
+package i18n
+
+// Переводы сообщений на английский
+var english = map[string]string{
+	// Запросы и общие ошибки
+	"Ошибка десериализации JSON":      "Invalid JSON",
+	"Метод не поддерживается":         "Method not allowed",
+	"Неизвестный адрес API":           "Unknown API endpoint",
+	"Некорректный идентификатор":      "Invalid ID",
+	"Не указан идентификатор":         "ID is required",
+	"Недостаточно прав":               "Insufficient permissions",
+	"недостаточно прав для изменения": "Insufficient permissions to modify",
+
+	// Вход, сессии и токены
+	"Слишком много попыток входа, попробуйте позже":   "Too many sign-in attempts, try again later",
+	"Ошибка при получении пользователя":               "Failed to get user",
+	"Неверный логин или пароль":                       "Invalid login or password",
+	"Требуется аутентификация":                        "Authentication required",
+	"Ошибка при получении сессии":                     "Failed to get session",
+	"Ошибка при получении сессий":                     "Failed to get sessions",
+	"Ошибка при создании сессии":                      "Failed to create session",
+	"Ошибка при обновлении сессии":                    "Failed to refresh session",
+	"Ошибка при закрытии сессии":                      "Failed to close session",
+	"сессия не найдена":                               "Session not found",
+	"Ошибка при создании токена":                      "Failed to create token",
+	"Недействительный refresh-токен":                  "Invalid refresh token",
+	"Запрос с чужого сайта отклонён":                  "Cross-site request rejected",
+	"ключ подписи не настроен":                        "Signing key is not configured",
+	"Ошибка при создании ключа подписи":               "Failed to create signing key",
+	"Недостаточно прав токена":                        "Insufficient token scope",
+	"Ошибка при проверке токена":                      "Failed to verify token",
+	"Управление токенами доступно только после входа": "Tokens can only be managed after signing in",
+	"Ошибка при получении токенов":                    "Failed to get tokens",
+	"токен не найден":                                 "Token not found",
+	"Ошибка при отзыве токена":                        "Failed to revoke token",
+	"Не указано название токена":                      "Token name is required",
+	"Не указаны области действия токена":              "Token scopes are required",
+	"Некорректная область действия: %s":               "Invalid scope: %s",
+	"Срок действия должен быть от 0 до %d дней":       "Expiration must be between 0 and %d days",
+
+	// Вход через OpenID Connect
+	"вход через OIDC не настроен":                            "OIDC sign-in is not configured",
+	"Вход через OIDC не настроен":                            "OIDC sign-in is not configured",
+	"учётная запись провайдера не сопоставлена пользователю": "Provider account is not linked to a user",
+	"Провайдер OIDC недоступен":                              "OIDC provider is unavailable",
+	"Ошибка при начале входа":                                "Failed to start sign-in",
+	"Провайдер OIDC отклонил вход":                           "OIDC provider rejected sign-in",
+	"Недействительный запрос входа":                          "Invalid sign-in request",
+	"Не удалось получить токен у провайдера OIDC":            "Failed to get token from OIDC provider",
+	"Недействительный ID токен":                              "Invalid ID token",
+	"Учётная запись не найдена":                              "Account not found",
+	"Вход через провайдера не начат или устарел":             "Provider sign-in has not been started or has expired",
+
+	// Двухфакторная аутентификация
+	"Требуется код двухфакторной аутентификации":                         "Two-factor authentication code required",
+	"Неверный код двухфакторной аутентификации":                          "Invalid two-factor authentication code",
+	"Ошибка при проверке кода":                                           "Failed to verify code",
+	"Ошибка при получении настроек":                                      "Failed to get settings",
+	"Ошибка при сохранении настроек":                                     "Failed to save settings",
+	"Необходимо включить двухфакторную аутентификацию":                   "Two-factor authentication must be enabled",
+	"Ошибка при получении кодов восстановления":                          "Failed to get recovery codes",
+	"Настройка двухфакторной аутентификации доступна только после входа": "Two-factor authentication can only be configured after signing in",
+	"Двухфакторная аутентификация уже включена":                          "Two-factor authentication is already enabled",
+	"Ошибка при создании секрета":                                        "Failed to create secret",
+	"Ошибка при сохранении секрета":                                      "Failed to save secret",
+	"Сначала получите секрет через /api/2fa/setup":                       "Get a secret via /api/2fa/setup first",
+	"Неверный код подтверждения":                                         "Invalid confirmation code",
+	"Ошибка при создании кодов восстановления":                           "Failed to create recovery codes",
+	"Ошибка при включении двухфакторной аутентификации":                  "Failed to enable two-factor authentication",
+	"Двухфакторная аутентификация не включена":                           "Two-factor authentication is not enabled",
+	"Двухфакторная аутентификация обязательна":                           "Two-factor authentication is required",
+	"Ошибка при отключении двухфакторной аутентификации":                 "Failed to disable two-factor authentication",
+
+	// Задачи
+	"задача не найдена":                                    "Task not found",
+	"задача изменена другим пользователем":                 "The task has been changed by someone else",
+	"некорректное правило повторения":                      "Invalid repeat rule",
+	"Ключ идемпотентности длиннее 255 символов":            "Idempotency key is longer than 255 characters",
+	"Ошибка при проверке ключа идемпотентности":            "Failed to check idempotency key",
+	"Ключ идемпотентности использован для другого запроса": "Idempotency key was used for a different request",
+	"Запрос с этим ключом идемпотентности ещё выполняется": "A request with this idempotency key is still in progress",
+	"Не указана версия задачи":                             "Task version is not specified",
+	"Некорректный идентификатор задачи":                    "Invalid task ID",
+	"Не указан идентификатор задачи":                       "Task ID is required",
+	"Не указан заголовок задачи":                           "Task title is required",
+	"Приоритет задачи должен быть от 1 до 4":               "Task priority must be between 1 and 4",
+	"Дата указана в неверном формате":                      "Invalid date format",
+	"Ошибка при получении задачи":                          "Failed to get task",
+	"Ошибка при получении списка задач":                    "Failed to get tasks",
+	"Ошибка при обновлении задачи":                         "Failed to update task",
+	"Ошибка при удалении задачи":                           "Failed to delete task",
+	"Ошибка при переносе задачи":                           "Failed to move task",
+	"Ошибка при расчете следующей даты":                    "Failed to calculate next date",
+	"задача заблокирована невыполненными задачами":         "Task is blocked by incomplete tasks",
+	"Режим фильтра по меткам должен быть any или all":      "Tag filter mode must be any or all",
+	"Сортировка должна быть date или priority":             "Sort must be date or priority",
+	"Ожидается тело application/merge-patch+json":          "Expected an application/merge-patch+json body",
+	"Поле нельзя изменить":                                 "Field cannot be changed",
+	"Некорректный тип значения поля":                       "Invalid field value type",
+
+	// Правила повторения
+	"время не может быть преобразовано в корректную дату": "Time cannot be converted to a valid date",
+	"пустое правило повторения":                           "Repeat rule is empty",
+	"указан неверный формат: %s":                          "Invalid format: %s",
+	"d %d — превышен максимально допустимый интервал":     "d %d exceeds the maximum allowed interval",
+	"указан неверный формат дня месяца: %s":               "Invalid day of month: %s",
+	"указан неверный формат месяца: %s":                   "Invalid month: %s",
+	"не удалось найти следующую подходящую дату":          "Failed to find the next matching date",
+
+	// Чек-листы и зависимости
+	"пункт чек-листа не найден":                     "Checklist item not found",
+	"Не указан текст пункта":                        "Checklist item text is required",
+	"Ошибка при получении чек-листа":                "Failed to get checklist",
+	"Ошибка при получении пункта чек-листа":         "Failed to get checklist item",
+	"Ошибка при удалении пункта чек-листа":          "Failed to delete checklist item",
+	"Ошибка при изменении порядка пунктов":          "Failed to reorder checklist items",
+	"Ошибка при обновлении чек-листа":               "Failed to update checklist",
+	"зависимость образует цикл":                     "Dependency creates a cycle",
+	"зависимость не найдена":                        "Dependency not found",
+	"Некорректный идентификатор блокирующей задачи": "Invalid blocking task ID",
+	"Ошибка при получении зависимостей":             "Failed to get dependencies",
+	"Ошибка при удалении зависимости":               "Failed to delete dependency",
+	"Ошибка при обновлении зависимостей":            "Failed to update dependencies",
+
+	// Вложения
+	"вложение не найдено":                 "Attachment not found",
+	"файл вложения не найден":             "Attachment file not found",
+	"файл не найден":                      "File not found",
+	"некорректный ключ файла":             "Invalid file key",
+	"Ошибка при получении вложений":       "Failed to get attachments",
+	"Ожидается форма multipart/form-data": "Expected multipart/form-data",
+	"Не передан файл":                     "No file provided",
+	"Ошибка чтения формы":                 "Failed to read form",
+	"Ошибка чтения файла":                 "Failed to read file",
+	"Ошибка при сохранении файла":         "Failed to save file",
+	"Размер файла превышает %d байт":      "File size exceeds %d bytes",
+	"Ошибка при сохранении вложения":      "Failed to save attachment",
+	"Ошибка при получении вложения":       "Failed to get attachment",
+	"Ошибка при чтении вложения":          "Failed to read attachment",
+	"Ошибка при удалении вложения":        "Failed to delete attachment",
+
+	// Проекты и метки
+	"проект не найден":                                    "Project not found",
+	"проект находится в архиве":                           "Project is archived",
+	"некорректный идентификатор проекта":                  "Invalid project ID",
+	"Некорректный идентификатор проекта":                  "Invalid project ID",
+	"задачу можно поместить только в проект её владельца": "A task can only be placed in its owner's project",
+	"Не указано название проекта":                         "Project name is required",
+	"Цвет проекта указан в неверном формате":              "Invalid project color format",
+	"Позиция проекта не может быть отрицательной":         "Project position cannot be negative",
+	"Ошибка при получении списка проектов":                "Failed to get projects",
+	"Ошибка при получении проекта":                        "Failed to get project",
+	"Ошибка при обновлении проекта":                       "Failed to update project",
+	"Ошибка при удалении проекта":                         "Failed to delete project",
+	"Ошибка при изменении порядка проектов":               "Failed to reorder projects",
+	"метка не найдена":                                    "Tag not found",
+	"метка с таким именем уже существует":                 "A tag with this name already exists",
+	"пустое имя метки":                                    "Tag name is empty",
+	"слишком длинное имя метки":                           "Tag name is too long",
+	"Некорректная метка":                                  "Invalid tag",
+	"Ошибка при получении списка меток":                   "Failed to get tags",
+	"Ошибка при получении метки":                          "Failed to get tag",
+	"Ошибка при удалении метки":                           "Failed to delete tag",
+
+	// Общий доступ
+	"доступ не найден":                       "Share not found",
+	"нужно указать либо задачу, либо проект": "Specify either a task or a project",
+	"Некорректный уровень доступа":           "Invalid permission",
+	"Нельзя открыть доступ самому себе":      "Cannot share with yourself",
+	"Ошибка при получении списка доступов":   "Failed to get shares",
+	"Ошибка при удалении доступа":            "Failed to delete share",
+
+	// Пользователи
+	"пользователь не найден":                                       "User not found",
+	"пользователь с таким логином уже существует":                  "A user with this login already exists",
+	"Ошибка при получении пользователей":                           "Failed to get users",
+	"Некорректный логин":                                           "Invalid login",
+	"Пароль должен содержать не менее %d символов":                 "Password must be at least %d characters long",
+	"Некорректная роль":                                            "Invalid role",
+	"Ошибка при сохранении пароля":                                 "Failed to save password",
+	"Нельзя изменить роль встроенного администратора":              "Cannot change the role of the built-in administrator",
+	"Нельзя удалить встроенного администратора или самого себя":    "Cannot delete the built-in administrator or yourself",
+	"Ошибка при получении задач пользователя":                      "Failed to get user's tasks",
+	"Ошибка при удалении задач пользователя":                       "Failed to delete user's tasks",
+	"Ошибка при удалении пользователя":                             "Failed to delete user",
+	"учётная запись провайдера уже связана с другим пользователем": "The provider account is already linked to another user",
+	"Учётная запись провайдера указывается в виде <issuer>|<sub>":  "The provider account must be in the form <issuer>|<sub>",
+
+	// Профиль
+	"Язык не поддерживается":        "Language is not supported",
+	"Ошибка при сохранении профиля": "Failed to save profile",
+
+	// Проверка по спецификации OpenAPI
+	"Обязательное поле":                           "Field is required",
+	"Неверный тип значения":                       "Invalid value type",
+	"Недопустимое значение":                       "Value is not allowed",
+	"Значение в неверном формате":                 "Invalid value format",
+	"Недопустимая длина значения":                 "Invalid value length",
+	"Значение вне допустимого диапазона":          "Value is out of range",
+	"Ошибка чтения запроса":                       "Failed to read request",
+	"Неизвестное поле":                            "Unknown field",
+	"Ответ не соответствует спецификации API: %s": "Response does not match the API specification: %s",
+
+	// Пакет операций над задачами
+	"Режим выполнения должен быть atomic или best_effort":     "Mode must be atomic or best_effort",
+	"Не указаны операции":                                     "No operations specified",
+	"В пакете может быть не больше %d операций":               "A batch may contain at most %d operations",
+	"Ошибка при выполнении пакета":                            "Failed to execute batch",
+	"Ошибка при выполнении операции":                          "Failed to execute operation",
+	"Операция отменена из-за ошибки в другой операции пакета": "Operation cancelled because another operation in the batch failed",
+	"Не указаны поля задачи":                                  "Task fields are not specified",
+	"Не указана дата":                                         "Date is not specified",
+	"Неизвестная операция: %s":                                "Unknown operation: %s",
+	"Некорректная версия задачи":                              "Invalid task version",
+}
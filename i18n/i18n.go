@@ -0,0 +1,118 @@
+// Package i18n переводит сообщения API на язык пользователя.
+// Исходные сообщения написаны на русском и служат ключами каталогов переводов:
+// чтобы добавить язык, достаточно добавить его каталог в catalogs
+package i18n
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Языки сообщений
+const (
+	Russian = "ru"
+	English = "en"
+)
+
+// Default — язык исходных сообщений, он же используется,
+// если клиент не указал поддерживаемый язык
+const Default = Russian
+
+// Каталоги переводов: исходное сообщение → перевод
+var catalogs = map[string]map[string]string{
+	English: english,
+}
+
+// Supported сообщает, поддерживается ли язык
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return lang == Default || ok
+}
+
+type languageKey struct{}
+
+// WithLanguage запоминает в контексте язык, выбранный пользователем в профиле
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+// Language возвращает язык ответа на запрос: выбранный пользователем,
+// а если он не выбран — наиболее предпочтительный из заголовка Accept-Language
+func Language(r *http.Request) string {
+	if lang, ok := r.Context().Value(languageKey{}).(string); ok && Supported(lang) {
+		return lang
+	}
+	return Negotiate(r.Header.Get("Accept-Language"))
+}
+
+// Negotiate выбирает поддерживаемый язык из значения заголовка Accept-Language,
+// например "en-US,en;q=0.9,ru;q=0.8". Региональные варианты сводятся к основному языку
+func Negotiate(header string) string {
+	best, bestQ := Default, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if lang == "*" {
+			lang = Default
+		}
+		if q > bestQ && Supported(lang) {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// Translate переводит сообщение на язык. Сообщения без перевода возвращаются как есть
+func Translate(lang, message string) string {
+	if translated, ok := catalogs[lang][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// T переводит сообщение на язык запроса
+func T(r *http.Request, message string) string {
+	return Translate(Language(r), message)
+}
+
+// Sprintf переводит шаблон сообщения на язык запроса и подставляет в него значения
+func Sprintf(r *http.Request, format string, args ...any) string {
+	return fmt.Sprintf(T(r, format), args...)
+}
+
+// Message — ошибка с параметрами, шаблон которой переводится при выводе пользователю
+type Message struct {
+	Format string
+	Args   []any
+}
+
+func (m *Message) Error() string {
+	return fmt.Sprintf(m.Format, m.Args...)
+}
+
+// Errorf создаёт ошибку, которую можно перевести, не разбирая её текст
+func Errorf(format string, args ...any) error {
+	return &Message{Format: format, Args: args}
+}
+
+// Error возвращает текст ошибки на языке запроса
+func Error(r *http.Request, err error) string {
+	var message *Message
+	if errors.As(err, &message) {
+		return Sprintf(r, message.Format, message.Args...)
+	}
+	return T(r, err.Error())
+}
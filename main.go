@@ -4,8 +4,10 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"todo-app/auth"
 	"todo-app/db"
 	"todo-app/router"
+	"todo-app/storage"
 
 	"github.com/joho/godotenv"
 )
@@ -20,6 +22,15 @@ func main() {
 	db.InitDB()
 	defer db.DB.Close()
 
+	// Создание администратора и перенос данных без владельца
+	auth.InitUsers()
+
+	// Загрузка ключей подписи токенов
+	auth.InitKeys()
+
+	// Инициализация хранилища вложений
+	storage.InitStorage(db.DB)
+
 	// Определение порта
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -0,0 +1,249 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"todo-app/apierr"
+	"todo-app/i18n"
+)
+
+// Middleware проверяет параметры и тело запросов к операциям из спецификации.
+// Запросы к адресам, которых нет в спецификации, передаются дальше без проверки.
+// При TODO_OPENAPI_VALIDATE_RESPONSES=true проверяются и ответы: этот режим
+// предназначен для тестов, ответ не по спецификации заменяется ошибкой 500
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		operation, pathParams := spec.find(r.Method, r.URL.Path)
+		if operation == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		violations, err := operation.validateRequest(r, pathParams)
+		if errors.Is(err, errInvalidJSON) {
+			apierr.WriteCode(w, r, http.StatusBadRequest, apierr.CodeInvalidJSON, "Ошибка десериализации JSON")
+			return
+		} else if err != nil {
+			apierr.Write(w, r, http.StatusBadRequest, "Ошибка чтения запроса")
+			return
+		}
+		if len(violations) > 0 {
+			writeViolations(w, r, violations)
+			return
+		}
+
+		if os.Getenv("TODO_OPENAPI_VALIDATE_RESPONSES") != "true" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		if err := operation.validateResponse(recorder); err != nil {
+			log.Printf("Response to %s %s does not match the OpenAPI specification: %v", r.Method, r.URL.Path, err)
+			apierr.Write(w, r, http.StatusInternalServerError, i18n.Sprintf(r, "Ответ не соответствует спецификации API: %s", err))
+			return
+		}
+		for name, values := range recorder.header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(recorder.status)
+		w.Write(recorder.body.Bytes())
+	})
+}
+
+var errInvalidJSON = errors.New("invalid JSON")
+
+// Проверяем параметры и тело запроса. Тело считывается целиком
+// и подставляется обратно, чтобы его мог прочитать обработчик
+func (o *Operation) validateRequest(r *http.Request, pathParams map[string]string) ([]violation, error) {
+	var violations []violation
+	query := r.URL.Query()
+	for _, parameter := range o.Parameters {
+		var values []string
+		switch parameter.In {
+		case "query":
+			values = query[parameter.Name]
+		case "path":
+			values = []string{pathParams[parameter.Name]}
+		case "header":
+			values = r.Header.Values(parameter.Name)
+		}
+		for _, v := range parameter.validate(values) {
+			if parameter.ErrorCode != "" {
+				v.code = parameter.ErrorCode
+			}
+			violations = append(violations, v)
+		}
+	}
+
+	if o.RequestBody == nil {
+		return violations, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, _ = mime.ParseMediaType(contentType)
+	}
+	// Тип содержимого, которого нет в спецификации, отклоняет сам обработчик
+	content, ok := o.RequestBody.Content[mediaType]
+	if !ok || content.Schema == nil {
+		return violations, nil
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		if len(bytes.TrimSpace(body)) == 0 && !o.RequestBody.Required {
+			return violations, nil
+		}
+		return nil, errInvalidJSON
+	}
+	return append(violations, content.Schema.validate(value, "")...), nil
+}
+
+// Проверяем значения параметра запроса
+func (p *Parameter) validate(values []string) []violation {
+	if len(values) == 0 {
+		if p.Required {
+			return []violation{{field: p.Name, message: "Обязательное поле"}}
+		}
+		return nil
+	}
+	if p.Schema == nil {
+		return nil
+	}
+
+	schema := p.Schema
+	if schema.target != nil {
+		schema = schema.target
+	}
+	if schema.Type == "array" {
+		items := make([]any, 0, len(values))
+		for _, value := range values {
+			if schema.Items != nil {
+				items = append(items, schema.Items.parseParameter(value))
+			} else {
+				items = append(items, value)
+			}
+		}
+		return schema.validate(items, p.Name)
+	}
+	return schema.validate(schema.parseParameter(values[0]), p.Name)
+}
+
+// Отправляем ошибки проверки запроса. Сообщение и код ошибки берутся
+// из первого нарушения, код — из x-error-code параметра, если он задан
+func writeViolations(w http.ResponseWriter, r *http.Request, violations []violation) {
+	first := violations[0]
+	code := apierr.CodeValidation
+	if first.code != "" {
+		code = first.code
+	}
+
+	// Нарушение без имени поля относится к телу запроса целиком
+	message := i18n.T(r, first.message)
+	if first.field != "" {
+		message = first.field + ": " + message
+	}
+	// Поля с ошибками перечисляются только для ошибок проверки:
+	// у некорректного идентификатора свой код и ответ как у обработчика
+	var fields map[string]string
+	if code == apierr.CodeValidation {
+		fields = make(map[string]string, len(violations))
+		for _, v := range violations {
+			if _, ok := fields[v.field]; !ok && v.field != "" {
+				fields[v.field] = i18n.T(r, v.message)
+			}
+		}
+	}
+	apierr.Respond(w, r, http.StatusBadRequest, apierr.Error{
+		Message: message,
+		Code:    code,
+		Fields:  fields,
+	})
+}
+
+// Проверяем записанный ответ: статус должен быть описан в спецификации,
+// а тело JSON — соответствовать схеме
+func (o *Operation) validateResponse(recorder *responseRecorder) error {
+	response, ok := o.Responses[strconv.Itoa(recorder.status)]
+	if !ok {
+		response, ok = o.Responses["default"]
+	}
+	if !ok {
+		return fmt.Errorf("status %d is not documented", recorder.status)
+	}
+
+	body := recorder.body.Bytes()
+	if len(response.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("status %d must not have a body", recorder.status)
+		}
+		return nil
+	}
+
+	contentType := recorder.header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	content, ok := response.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("content type %s is not documented for status %d", mediaType, recorder.status)
+	}
+	if content.Schema == nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	if violations := content.Schema.validate(value, ""); len(violations) > 0 {
+		messages := make([]string, 0, len(violations))
+		for _, v := range violations {
+			messages = append(messages, v.String())
+		}
+		return errors.New(strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// Ответ обработчика, который отправляется клиенту только после проверки
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(data)
+}
@@ -0,0 +1,213 @@
+// Package openapi раздаёт спецификацию API в формате OpenAPI 3 и проверяет
+// по ней запросы, а в режиме тестов — и ответы сервера
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//go:embed openapi.json
+var specJSON []byte
+
+// Document — разобранная спецификация. Поля, не нужные для проверок, не разбираются
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas    map[string]*Schema    `json:"schemas"`
+		Parameters map[string]*Parameter `json:"parameters"`
+		Responses  map[string]*Response  `json:"responses"`
+	} `json:"components"`
+
+	routes []route
+}
+
+// Operation — метод по адресу из спецификации
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter — параметр запроса. Расширение x-error-code задаёт код ошибки,
+// если параметр не прошёл проверку (например, invalid_id для идентификаторов)
+type Parameter struct {
+	Ref       string  `json:"$ref"`
+	Name      string  `json:"name"`
+	In        string  `json:"in"`
+	Required  bool    `json:"required"`
+	ErrorCode string  `json:"x-error-code"`
+	Schema    *Schema `json:"schema"`
+}
+
+// RequestBody — тело запроса по типам содержимого
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response — ответ по типам содержимого. Ответ без содержимого не имеет тела
+type Response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Адрес из спецификации, разбитый на сегменты; сегменты {name} — параметры пути
+type route struct {
+	path     string
+	segments []string
+}
+
+// Спецификация разбирается один раз при запуске: ошибка в ней — ошибка сборки
+var spec = mustLoad(specJSON)
+
+func mustLoad(data []byte) *Document {
+	doc, err := load(data)
+	if err != nil {
+		panic(fmt.Sprintf("openapi.json: %v", err))
+	}
+	return doc
+}
+
+func load(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	for _, schema := range doc.Components.Schemas {
+		if err := schema.prepare(&doc); err != nil {
+			return nil, err
+		}
+	}
+	for name, parameter := range doc.Components.Parameters {
+		if err := parameter.Schema.prepare(&doc); err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", name, err)
+		}
+	}
+	for name, response := range doc.Components.Responses {
+		if err := response.prepare(&doc); err != nil {
+			return nil, fmt.Errorf("response %s: %w", name, err)
+		}
+	}
+
+	for path, operations := range doc.Paths {
+		for method, operation := range operations {
+			if err := operation.prepare(&doc); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+		}
+		doc.routes = append(doc.routes, route{path: path, segments: strings.Split(path, "/")})
+	}
+
+	// Адреса без параметров проверяются первыми, чтобы /a/b не совпал с /a/{id}
+	sort.Slice(doc.routes, func(i, j int) bool {
+		pi, pj := strings.Count(doc.routes[i].path, "{"), strings.Count(doc.routes[j].path, "{")
+		if pi != pj {
+			return pi < pj
+		}
+		return doc.routes[i].path < doc.routes[j].path
+	})
+	return &doc, nil
+}
+
+// Подставляем параметры и ответы по $ref и подготавливаем схемы операции
+func (o *Operation) prepare(doc *Document) error {
+	for i, parameter := range o.Parameters {
+		if parameter.Ref != "" {
+			name, ok := refName(parameter.Ref, "#/components/parameters/")
+			target := doc.Components.Parameters[name]
+			if !ok || target == nil {
+				return fmt.Errorf("unknown parameter %s", parameter.Ref)
+			}
+			o.Parameters[i] = target
+			continue
+		}
+		if err := parameter.Schema.prepare(doc); err != nil {
+			return err
+		}
+	}
+	if o.RequestBody != nil {
+		for _, mediaType := range o.RequestBody.Content {
+			if err := mediaType.Schema.prepare(doc); err != nil {
+				return err
+			}
+		}
+	}
+	for status, response := range o.Responses {
+		if response.Ref != "" {
+			name, ok := refName(response.Ref, "#/components/responses/")
+			target := doc.Components.Responses[name]
+			if !ok || target == nil {
+				return fmt.Errorf("unknown response %s", response.Ref)
+			}
+			o.Responses[status] = target
+			continue
+		}
+		if err := response.prepare(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Response) prepare(doc *Document) error {
+	for _, mediaType := range r.Content {
+		if err := mediaType.Schema.prepare(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func refName(ref, prefix string) (string, bool) {
+	return strings.CutPrefix(ref, prefix)
+}
+
+// Находим операцию для запроса и значения параметров пути
+func (d *Document) find(method, path string) (*Operation, map[string]string) {
+	segments := strings.Split(path, "/")
+	for _, route := range d.routes {
+		params, ok := route.match(segments)
+		if !ok {
+			continue
+		}
+		operation := d.Paths[route.path][strings.ToLower(method)]
+		if operation == nil {
+			return nil, nil
+		}
+		return operation, params
+	}
+	return nil, nil
+}
+
+func (r route) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, segment := range r.segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params[segment[1:len(segment)-1]] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// Handler отдаёт спецификацию API
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Write(specJSON)
+}
@@ -0,0 +1,209 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Schema — подмножество JSON Schema из OpenAPI 3.0, которого достаточно для описания API
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	Pattern              string             `json:"pattern"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	Items                *Schema            `json:"items"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AllOf                []*Schema          `json:"allOf"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+
+	target     *Schema        // схема, на которую указывает $ref
+	pattern    *regexp.Regexp // скомпилированный Pattern
+	additional *Schema        // схема дополнительных полей объекта
+	closed     bool           // additionalProperties: false
+}
+
+// Нарушение схемы: путь к полю и исходное сообщение, которое переводится при выводе
+type violation struct {
+	field   string
+	message string
+	code    string // код ошибки, если он отличается от validation_failed
+}
+
+func (v violation) String() string {
+	return v.field + ": " + v.message
+}
+
+// Подготавливаем схему к проверкам: находим схемы по $ref, компилируем шаблоны
+func (s *Schema) prepare(doc *Document) error {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		name, ok := refName(s.Ref, "#/components/schemas/")
+		target := doc.Components.Schemas[name]
+		if !ok || target == nil {
+			return fmt.Errorf("unknown schema %s", s.Ref)
+		}
+		s.target = target
+		return nil
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = pattern
+	}
+	if len(s.AdditionalProperties) > 0 {
+		if string(s.AdditionalProperties) == "false" {
+			s.closed = true
+		} else if string(s.AdditionalProperties) != "true" {
+			s.additional = &Schema{}
+			if err := json.Unmarshal(s.AdditionalProperties, s.additional); err != nil {
+				return err
+			}
+		}
+	}
+
+	children := append([]*Schema{s.Items, s.additional}, s.AllOf...)
+	for _, property := range s.Properties {
+		children = append(children, property)
+	}
+	for _, child := range children {
+		if err := child.prepare(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Проверяем значение, разобранное encoding/json
+func (s *Schema) validate(value any, field string) []violation {
+	if s.target != nil {
+		return s.target.validate(value, field)
+	}
+
+	var violations []violation
+	for _, part := range s.AllOf {
+		violations = append(violations, part.validate(value, field)...)
+	}
+
+	if value == nil {
+		if s.Type != "" && !s.Nullable {
+			violations = append(violations, violation{field: field, message: "Неверный тип значения"})
+		}
+		return violations
+	}
+	if !hasType(value, s.Type) {
+		return append(violations, violation{field: field, message: "Неверный тип значения"})
+	}
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, value) {
+		violations = append(violations, violation{field: field, message: "Недопустимое значение"})
+	}
+
+	switch value := value.(type) {
+	case string:
+		if s.pattern != nil && !s.pattern.MatchString(value) {
+			violations = append(violations, violation{field: field, message: "Значение в неверном формате"})
+		}
+		length := utf8.RuneCountInString(value)
+		if (s.MinLength != nil && length < *s.MinLength) || (s.MaxLength != nil && length > *s.MaxLength) {
+			violations = append(violations, violation{field: field, message: "Недопустимая длина значения"})
+		}
+	case float64:
+		if (s.Minimum != nil && value < *s.Minimum) || (s.Maximum != nil && value > *s.Maximum) {
+			violations = append(violations, violation{field: field, message: "Значение вне допустимого диапазона"})
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range value {
+				violations = append(violations, s.Items.validate(item, fmt.Sprintf("%s[%d]", field, i))...)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				violations = append(violations, violation{field: joinField(field, name), message: "Обязательное поле"})
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			switch property, ok := s.Properties[name]; {
+			case ok:
+				violations = append(violations, property.validate(value[name], joinField(field, name))...)
+			case s.closed:
+				violations = append(violations, violation{field: joinField(field, name), message: "Неизвестное поле"})
+			case s.additional != nil:
+				violations = append(violations, s.additional.validate(value[name], joinField(field, name))...)
+			}
+		}
+	}
+	return violations
+}
+
+// Проверяем тип значения, разобранного encoding/json
+func hasType(value any, typ string) bool {
+	switch typ {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	}
+	return true
+}
+
+// Приводим строковое значение параметра запроса к типу схемы.
+// Значение, которое не приводится, возвращается строкой и не пройдёт проверку типа
+func (s *Schema) parseParameter(value string) any {
+	if s.target != nil {
+		return s.target.parseParameter(value)
+	}
+	switch s.Type {
+	case "integer", "number":
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case "boolean":
+		if flag, err := strconv.ParseBool(value); err == nil {
+			return flag
+		}
+	}
+	return value
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -2,8 +2,10 @@ package router
 
 import (
 	"net/http"
+	"todo-app/apierr"
 	"todo-app/auth"
 	"todo-app/handlers"
+	"todo-app/openapi"
 
 	"github.com/gorilla/mux"
 )
@@ -11,11 +13,70 @@ import (
 // Создаем роутер
 func NewRouter() *mux.Router {
 	r := mux.NewRouter()
-	r.HandleFunc("/api/signin", auth.SigninHandler).Methods("POST")
-	r.HandleFunc("/api/nextdate", handlers.NextDateHandler).Methods("GET")
-	r.Handle("/api/task", auth.AuthMiddleware(http.HandlerFunc(handlers.TaskHandler))).Methods("POST", "PUT", "GET", "DELETE")
-	r.Handle("/api/task/done", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleCompleteTask))).Methods("POST")
-	r.Handle("/api/tasks", auth.AuthMiddleware(http.HandlerFunc(handlers.GetTasksHandler))).Methods("GET")
+	r.Use(auth.CSRFMiddleware)
+
+	// Запросы проверяются по спецификации OpenAPI после аутентификации,
+	// чтобы ошибки проверки были на языке пользователя
+	public := func(h http.HandlerFunc) http.Handler {
+		return openapi.Middleware(h)
+	}
+	// Сессии, токены и второй фактор доступны всем ролям
+	authed := func(h http.HandlerFunc) http.Handler {
+		return auth.AuthMiddleware(openapi.Middleware(h))
+	}
+	// Данные пользователя: читать могут все роли, изменять — участники и администраторы
+	data := func(h http.HandlerFunc) http.Handler {
+		return auth.AuthMiddleware(auth.WriteAccessMiddleware(openapi.Middleware(h)))
+	}
+	// Управление пользователями и настройками — только для администраторов
+	admin := func(h http.HandlerFunc) http.Handler {
+		return auth.AuthMiddleware(auth.AdminMiddleware(openapi.Middleware(h)))
+	}
+
+	r.Handle("/api/openapi.json", public(openapi.Handler)).Methods("GET")
+	r.Handle("/api/signin", public(auth.SigninHandler)).Methods("POST")
+	r.HandleFunc("/api/token/refresh", auth.RefreshHandler).Methods("POST")
+	r.HandleFunc("/api/oidc/login", auth.OIDCLoginHandler).Methods("GET")
+	r.HandleFunc("/api/oidc/callback", auth.OIDCCallbackHandler).Methods("GET")
+	r.Handle("/api/oidc/2fa", public(auth.OIDCTwoFactorHandler)).Methods("POST")
+	r.Handle("/api/signout", authed(auth.SignoutHandler)).Methods("POST")
+	r.Handle("/api/sessions", authed(auth.SessionsHandler)).Methods("GET", "DELETE")
+	r.Handle("/api/tokens", authed(auth.TokensHandler)).Methods("GET", "POST", "DELETE")
+	r.Handle("/api/2fa", authed(auth.TwoFactorHandler)).Methods("GET")
+	r.Handle("/api/2fa/setup", authed(auth.TwoFactorSetupHandler)).Methods("POST")
+	r.Handle("/api/2fa/enable", authed(auth.TwoFactorEnableHandler)).Methods("POST")
+	r.Handle("/api/2fa/disable", authed(auth.TwoFactorDisableHandler)).Methods("POST")
+	r.Handle("/api/profile", authed(handlers.ProfileHandler)).Methods("GET", "PUT")
+	r.Handle("/api/nextdate", public(handlers.NextDateHandler)).Methods("GET")
+	r.Handle("/api/task", data(handlers.Idempotent(handlers.TaskHandler))).Methods("POST", "PUT", "GET", "DELETE")
+	r.Handle("/api/task/done", data(handlers.Idempotent(handlers.HandleCompleteTask))).Methods("POST")
+	r.Handle("/api/task/checklist", data(handlers.ChecklistHandler)).Methods("POST", "PUT", "GET", "DELETE")
+	r.Handle("/api/task/checklist/reorder", data(handlers.HandleReorderChecklist)).Methods("POST")
+	r.Handle("/api/task/dependencies", data(handlers.DependencyHandler)).Methods("POST", "GET", "DELETE")
+	r.Handle("/api/task/attachments", data(handlers.AttachmentsHandler)).Methods("POST", "GET")
+	r.Handle("/api/attachment", data(handlers.AttachmentHandler)).Methods("GET", "DELETE")
+	r.Handle("/api/task/move", data(handlers.HandleMoveTask)).Methods("POST")
+	r.Handle("/api/tasks", data(handlers.GetTasksHandler)).Methods("GET")
+	r.Handle("/api/tasks/batch", data(handlers.Idempotent(handlers.HandleTaskBatch))).Methods("POST")
+	r.Handle("/api/v2/tasks/{id:[0-9]+}", data(handlers.TaskV2Handler)).Methods("GET", "PATCH", "DELETE")
+	r.Handle("/api/v2/tasks/{id:[0-9]+}/complete", data(handlers.Idempotent(handlers.HandleCompleteTaskV2))).Methods("POST")
+	r.Handle("/api/tag", data(handlers.TagHandler)).Methods("POST", "PUT", "GET", "DELETE")
+	r.Handle("/api/tags", data(handlers.GetTagsHandler)).Methods("GET")
+	r.Handle("/api/project", data(handlers.ProjectHandler)).Methods("POST", "PUT", "GET", "DELETE")
+	r.Handle("/api/project/archive", data(handlers.HandleArchiveProject)).Methods("POST")
+	r.Handle("/api/project/unarchive", data(handlers.HandleUnarchiveProject)).Methods("POST")
+	r.Handle("/api/projects", data(handlers.GetProjectsHandler)).Methods("GET")
+	r.Handle("/api/projects/reorder", data(handlers.HandleReorderProjects)).Methods("POST")
+	r.Handle("/api/share", data(handlers.ShareHandler)).Methods("POST", "GET", "DELETE")
+	r.Handle("/api/shared", data(handlers.GetSharedHandler)).Methods("GET")
+	r.Handle("/api/admin/users", admin(handlers.UsersHandler)).Methods("POST", "GET", "PUT", "DELETE")
+	r.Handle("/api/admin/settings", admin(handlers.SettingsHandler)).Methods("GET", "PUT")
+	r.Handle("/api/admin/keys/rotate", admin(auth.RotateKeysHandler)).Methods("POST")
+
+	// Неизвестные адреса API отвечают ошибкой в формате JSON, а не страницей файлового сервера
+	r.PathPrefix("/api/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apierr.Write(w, r, http.StatusNotFound, "Неизвестный адрес API")
+	})
 
 	// Маршрут для файлов фронтенда
 	webDir := "./web"
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Определение пользовательской ошибки
+var ErrFileNotFound = errors.New("файл не найден")
+
+// Хранилище содержимого вложений. Метаданные вложений хранятся в базе данных,
+// а хранилище отвечает только за байты файла по его ключу
+type Storage interface {
+	Save(key string, r io.Reader) (int64, error)
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// Хранилище, выбранное при запуске приложения
+var Files Storage
+
+// Инициализируем хранилище вложений согласно переменным окружения
+func InitStorage(database *sql.DB) {
+	switch os.Getenv("TODO_ATTACHMENTS_STORAGE") {
+	case "", "disk":
+		dir := os.Getenv("TODO_ATTACHMENTS_DIR")
+		if dir == "" {
+			dir = "attachments"
+		}
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			log.Fatalf("Failed to create attachments directory: %v", err)
+		}
+		log.Printf("Using attachments directory: %s", dir)
+		Files = &DiskStorage{Dir: dir}
+	case "db":
+		log.Println("Storing attachments in the database")
+		Files = &DBStorage{DB: database}
+	default:
+		log.Fatalf("Unknown attachments storage: %s", os.Getenv("TODO_ATTACHMENTS_STORAGE"))
+	}
+}
+
+// Генерируем случайный ключ для нового файла
+func NewKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Ключ может состоять только из шестнадцатеричных символов,
+// поэтому из него нельзя составить путь за пределами каталога хранилища
+var keyPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+var errInvalidKey = errors.New("некорректный ключ файла")
+
+// Хранилище файлов в каталоге на диске
+type DiskStorage struct {
+	Dir string
+}
+
+func (s *DiskStorage) path(key string) (string, error) {
+	if !keyPattern.MatchString(key) {
+		return "", errInvalidKey
+	}
+	return filepath.Join(s.Dir, key), nil
+}
+
+// Сохраняем файл; при ошибке частично записанный файл удаляется
+func (s *DiskStorage) Save(key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+	return size, nil
+}
+
+func (s *DiskStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFileNotFound
+	}
+	return f, err
+}
+
+func (s *DiskStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Хранилище файлов внутри базы данных SQLite, в таблице attachment_blobs
+type DBStorage struct {
+	DB *sql.DB
+}
+
+func (s *DBStorage) Save(key string, r io.Reader) (int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.DB.Exec(`INSERT INTO attachment_blobs (key, data) VALUES (?, ?)`, key, data); err != nil {
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
+
+func (s *DBStorage) Open(key string) (io.ReadCloser, error) {
+	var data []byte
+	err := s.DB.QueryRow(`SELECT data FROM attachment_blobs WHERE key = ?`, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFileNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *DBStorage) Delete(key string) error {
+	_, err := s.DB.Exec(`DELETE FROM attachment_blobs WHERE key = ?`, key)
+	return err
+}
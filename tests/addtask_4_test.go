@@ -60,6 +60,51 @@ func requestJSON(apipath string, values map[string]any, method string) ([]byte,
 	return io.ReadAll(resp.Body)
 }
 
+// Выполняем запрос с дополнительными заголовками и возвращаем статус, заголовки и тело ответа.
+// values кодируется в JSON, а io.Reader отправляется как есть. Если в headers нет
+// Cookie или Authorization, запрос выполняется с токеном из настроек
+func request(t *testing.T, apipath string, values any, method string, headers map[string]string) (int, http.Header, map[string]any) {
+	var body io.Reader
+	switch v := values.(type) {
+	case io.Reader:
+		body = v
+	case map[string]any:
+		if len(v) > 0 {
+			data, err := json.Marshal(v)
+			assert.NoError(t, err)
+			body = bytes.NewReader(data)
+		}
+	case nil:
+	default:
+		data, err := json.Marshal(v)
+		assert.NoError(t, err)
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, getURL(apipath), body)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	if req.Header.Get("Cookie") == "" && req.Header.Get("Authorization") == "" && len(Token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var m map[string]any
+	json.NewDecoder(resp.Body).Decode(&m)
+	return resp.StatusCode, resp.Header, m
+}
+
+// Заголовки запроса от имени пользователя с указанным токеном сессии
+func asUser(token string) map[string]string {
+	return map[string]string{"Cookie": "token=" + token}
+}
+
 func postJSON(apipath string, values map[string]any, method string) (map[string]any, error) {
 	var (
 		m   map[string]any
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func uploadFile(t *testing.T, taskID, name string, content []byte) (int, map[string]any) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", name)
+	assert.NoError(t, err)
+	part.Write(content)
+	assert.NoError(t, form.Close())
+
+	code, _, m := request(t, "api/task/attachments?task_id="+taskID, &body, http.MethodPost,
+		map[string]string{"Content-Type": form.FormDataContentType()})
+	assert.NotNil(t, m)
+	return code, m
+}
+
+func TestAttachments(t *testing.T) {
+	id := addTask(t, task{title: "Оплатить счёт"})
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+	status, ret := uploadFile(t, id, "../../счёт.png", png)
+	assert.Equal(t, http.StatusOK, status)
+	attachment := fmt.Sprint(ret["id"])
+
+	body, err := requestJSON("api/task/attachments?task_id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list struct {
+		Attachments []struct {
+			ID          string `json:"id"`
+			Name        string `json:"name"`
+			ContentType string `json:"content_type"`
+			Size        int    `json:"size"`
+		} `json:"attachments"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &list))
+	if assert.Len(t, list.Attachments, 1) {
+		assert.Equal(t, "счёт.png", list.Attachments[0].Name)
+		assert.Equal(t, "image/png", list.Attachments[0].ContentType)
+		assert.Equal(t, len(png), list.Attachments[0].Size)
+	}
+
+	content, err := requestJSON("api/attachment?id="+attachment, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, png, content)
+	code, header, _ := request(t, "api/attachment?id="+attachment, nil, http.MethodGet, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "image/png", header.Get("Content-Type"))
+
+	status, ret = uploadFile(t, id, "big.bin", bytes.Repeat([]byte("x"), 11<<20))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+	assert.NotEmpty(t, ret["error"])
+
+	status, _ = uploadFile(t, "7645346343", "note.txt", []byte("заметка"))
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// При удалении задачи удаляются и её вложения
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	ret, err = postJSON("api/attachment?id="+attachment, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+}
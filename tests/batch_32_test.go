@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Выполняем пакет операций и возвращаем признак сохранения и результаты операций
+func postBatch(t *testing.T, mode string, operations ...map[string]any) (bool, []map[string]any) {
+	values := map[string]any{"operations": operations}
+	if mode != "" {
+		values["mode"] = mode
+	}
+	code, _, ret := request(t, "api/tasks/batch", values, http.MethodPost, nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	committed, _ := ret["committed"].(bool)
+	var results []map[string]any
+	items, _ := ret["results"].([]any)
+	for _, item := range items {
+		result, _ := item.(map[string]any)
+		results = append(results, result)
+	}
+	assert.Len(t, results, len(operations))
+	return committed, results
+}
+
+// Коды ответа операций пакета по порядку
+func batchStatuses(results []map[string]any) []float64 {
+	statuses := make([]float64, 0, len(results))
+	for _, result := range results {
+		status, _ := result["status"].(float64)
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Код ошибки операции пакета
+func batchErrorCode(result map[string]any) string {
+	e, _ := result["error"].(map[string]any)
+	code, _ := e["code"].(string)
+	return code
+}
+
+func TestTaskBatch(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	today := now.Format(`20060102`)
+
+	ret, err := postJSON("api/task", map[string]any{"title": "Разобрать почту", "repeat": "d 1"}, http.MethodPost)
+	assert.NoError(t, err)
+	repeating, _ := ret["id"].(string)
+	assert.NotEmpty(t, repeating)
+	defer request(t, "api/task?id="+repeating, nil, http.MethodDelete, nil)
+
+	ret, err = postJSON("api/task", map[string]any{"title": "Сдать отчёт"}, http.MethodPost)
+	assert.NoError(t, err)
+	single, _ := ret["id"].(string)
+	assert.NotEmpty(t, single)
+	defer request(t, "api/task?id="+single, nil, http.MethodDelete, nil)
+
+	before, err := count(db)
+	assert.NoError(t, err)
+
+	// Всё или ничего: ошибка одной операции отменяет остальные
+	committed, results := postBatch(t, "atomic",
+		map[string]any{"op": "create", "task": map[string]any{"title": "Новая задача из пакета"}},
+		map[string]any{"op": "complete", "id": repeating},
+		map[string]any{"op": "delete", "id": "999999999"},
+	)
+	assert.False(t, committed)
+	assert.Equal(t, []float64{http.StatusFailedDependency, http.StatusFailedDependency, http.StatusNotFound}, batchStatuses(results))
+	assert.Equal(t, "batch_aborted", batchErrorCode(results[0]))
+	assert.Equal(t, "not_found", batchErrorCode(results[2]))
+
+	after, err := count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	var task Task
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id = ?`, repeating))
+	assert.Equal(t, today, task.Date)
+	assert.Equal(t, int64(1), task.Version)
+
+	// Некорректная операция отклоняет пакет ещё до выполнения
+	committed, results = postBatch(t, "atomic",
+		map[string]any{"op": "delete", "id": single},
+		map[string]any{"op": "reschedule", "id": repeating, "date": "20261340"},
+	)
+	assert.False(t, committed)
+	assert.Equal(t, []float64{http.StatusFailedDependency, http.StatusBadRequest}, batchStatuses(results))
+	assert.Equal(t, "validation_failed", batchErrorCode(results[1]))
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id = ?`, single))
+
+	// По возможности: неудачная операция пропускается, остальные сохраняются
+	next := now.AddDate(0, 0, 7).Format(`20060102`)
+	committed, results = postBatch(t, "best_effort",
+		map[string]any{"op": "create", "task": map[string]any{"title": "Задача из пакета #пакет"}},
+		map[string]any{"op": "update", "id": single, "version": "1", "task": map[string]any{"title": "Сдать отчёт до пятницы", "priority": 1}},
+		map[string]any{"op": "update", "id": single, "version": "1", "task": map[string]any{"title": "Устаревшее изменение"}},
+		map[string]any{"op": "reschedule", "id": repeating, "date": next},
+		map[string]any{"op": "complete", "id": repeating},
+		map[string]any{"op": "delete", "id": "999999999"},
+	)
+	assert.True(t, committed)
+	assert.Equal(t, []float64{http.StatusOK, http.StatusOK, http.StatusPreconditionFailed, http.StatusOK, http.StatusOK, http.StatusNotFound},
+		batchStatuses(results))
+	assert.Equal(t, "precondition_failed", batchErrorCode(results[2]))
+
+	created, _ := results[0]["id"].(string)
+	assert.NotEmpty(t, created)
+	assert.Equal(t, "1", results[0]["version"])
+	defer request(t, "api/task?id="+created, nil, http.MethodDelete, nil)
+
+	code, _, got := request(t, "api/task?id="+created, nil, http.MethodGet, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []any{"пакет"}, got["tags"])
+
+	assert.Equal(t, "2", results[1]["version"])
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id = ?`, single))
+	assert.Equal(t, "Сдать отчёт до пятницы", task.Title)
+	assert.Equal(t, 1, task.Priority)
+
+	// Операции видят изменения предыдущих: задача перенесена, а затем выполнена
+	assert.Equal(t, "3", results[4]["version"])
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id = ?`, repeating))
+	assert.Equal(t, now.AddDate(0, 0, 8).Format(`20060102`), task.Date)
+	assert.Equal(t, int64(3), task.Version)
+
+	// Выполнение обычной задачи удаляет её
+	committed, results = postBatch(t, "", map[string]any{"op": "complete", "id": single, "version": "2"})
+	assert.True(t, committed)
+	assert.Equal(t, []float64{http.StatusOK}, batchStatuses(results))
+	assert.Empty(t, results[0]["version"])
+	notFoundTask(t, single)
+
+	// Ошибки самого запроса
+	code, _, got = request(t, "api/tasks/batch", map[string]any{"mode": "sometimes", "operations": []any{}}, http.MethodPost, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, got["fields"], "mode")
+
+	code, _, got = request(t, "api/tasks/batch", map[string]any{"operations": []any{}}, http.MethodPost, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, got["fields"], "operations")
+
+	operations := make([]map[string]any, 101)
+	for i := range operations {
+		operations[i] = map[string]any{"op": "delete", "id": "999999999"}
+	}
+	code, _, got = request(t, "api/tasks/batch", map[string]any{"operations": operations}, http.MethodPost, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, got["fields"], "operations")
+}
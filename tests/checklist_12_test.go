@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type checklistItem struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+func getChecklist(t *testing.T, taskID string) []checklistItem {
+	body, err := requestJSON("api/task/checklist?task_id="+taskID, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m struct {
+		Items []checklistItem `json:"items"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m.Items
+}
+
+func TestChecklist(t *testing.T) {
+	id := addTask(t, task{
+		title:  "Выпустить релиз",
+		repeat: "d 14",
+	})
+
+	var items []string
+	for _, title := range []string{"Собрать", "Протестировать", "Опубликовать"} {
+		ret, err := postJSON("api/task/checklist", map[string]any{
+			"task_id": id,
+			"title":   title,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		items = append(items, fmt.Sprint(ret["id"]))
+	}
+
+	ret, err := postJSON("api/task/checklist", map[string]any{
+		"id":    items[0],
+		"title": "Собрать",
+		"done":  true,
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	task, err := postJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"total": float64(3), "done": float64(1)}, task["checklist"])
+
+	ret, err = postJSON("api/task/checklist/reorder", map[string]any{
+		"task_id": id,
+		"ids":     []string{items[2], items[0], items[1]},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	list := getChecklist(t, id)
+	if assert.Len(t, list, 3) {
+		assert.Equal(t, items[2], list[0].ID)
+		assert.Equal(t, items[0], list[1].ID)
+		assert.True(t, list[1].Done)
+	}
+
+	// Выполнение повторяющейся задачи сбрасывает отметки чек-листа
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	for _, item := range getChecklist(t, id) {
+		assert.False(t, item.Done, "Пункт %s должен быть сброшен", item.Title)
+	}
+
+	ret, err = postJSON("api/task/checklist?id="+items[1], nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Len(t, getChecklist(t, id), 2)
+
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	ret, err = postJSON("api/task/checklist?task_id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+}
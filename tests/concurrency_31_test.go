@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Выполняем одну и ту же задачу параллельными запросами и считаем ответы по статусам
+func completeConcurrently(t *testing.T, id string, n int, headers map[string]string) map[int]int {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		statuses = map[int]int{}
+	)
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			code, _, _ := request(t, "api/task/done?id="+id, nil, http.MethodPost, headers)
+			mu.Lock()
+			statuses[code]++
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return statuses
+}
+
+func TestConcurrentComplete(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	const n = 10
+	now := time.Now()
+
+	// Без версии каждый успешный запрос переносит задачу ровно на один интервал:
+	// изменения не теряются и не применяются дважды
+	ret, err := postJSON("api/task", map[string]any{"title": "Полить цветы", "repeat": "d 1"}, http.MethodPost)
+	assert.NoError(t, err)
+	id, _ := ret["id"].(string)
+	assert.NotEmpty(t, id)
+	defer request(t, "api/task?id="+id, nil, http.MethodDelete, nil)
+
+	statuses := completeConcurrently(t, id, n, nil)
+	done := statuses[http.StatusOK]
+	assert.Positive(t, done)
+	assert.Equal(t, n, done+statuses[http.StatusConflict])
+
+	var task Task
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id = ?`, id))
+	assert.Equal(t, now.AddDate(0, 0, done).Format(`20060102`), task.Date)
+	assert.Equal(t, int64(1+done), task.Version)
+
+	// С одинаковым If-Match задачу выполняет ровно один запрос
+	statuses = completeConcurrently(t, id, n, map[string]string{"If-Match": fmt.Sprintf(`"%d"`, task.Version)})
+	assert.Equal(t, map[int]int{http.StatusOK: 1, http.StatusPreconditionFailed: n - 1}, statuses)
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id = ?`, id))
+	assert.Equal(t, now.AddDate(0, 0, done+1).Format(`20060102`), task.Date)
+	assert.Equal(t, int64(2+done), task.Version)
+
+	// С одинаковым ключом идемпотентности — тоже
+	key := fmt.Sprintf("complete-%d", time.Now().UnixNano())
+	statuses = completeConcurrently(t, id, n, map[string]string{"Idempotency-Key": key})
+	assert.Zero(t, statuses[http.StatusInternalServerError])
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id = ?`, id))
+	assert.Equal(t, now.AddDate(0, 0, done+2).Format(`20060102`), task.Date)
+
+	// Обычная задача удаляется один раз, остальные запросы её уже не находят
+	ret, err = postJSON("api/task", map[string]any{"title": "Вынести мусор"}, http.MethodPost)
+	assert.NoError(t, err)
+	id, _ = ret["id"].(string)
+	assert.NotEmpty(t, id)
+
+	statuses = completeConcurrently(t, id, n, nil)
+	assert.Equal(t, 1, statuses[http.StatusOK])
+	assert.Equal(t, n-1, statuses[http.StatusNotFound]+statuses[http.StatusConflict])
+	assert.Zero(t, statuses[http.StatusInternalServerError])
+	notFoundTask(t, id)
+}
@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSRF(t *testing.T) {
+	now := time.Now().Format(`20060102`)
+	origin := strings.TrimSuffix(getURL(""), "/")
+	task := map[string]any{"date": now, "title": "Защита от CSRF"}
+
+	code, _, ret := request(t, "api/task", task, http.MethodPost, map[string]string{"Origin": origin})
+	assert.Equal(t, http.StatusOK, code)
+	id := fmt.Sprint(ret["id"])
+
+	// Изменяющие запросы с чужого сайта отклоняются
+	evil := map[string]string{"Origin": "http://evil.example"}
+	code, _, _ = request(t, "api/task", task, http.MethodPost, evil)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = request(t, "api/task/done?id="+id, nil, http.MethodPost, evil)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = request(t, "api/task?id="+id, nil, http.MethodDelete, map[string]string{"Referer": "http://evil.example/page"})
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = request(t, "api/task?id="+id, nil, http.MethodDelete, map[string]string{"Origin": "null"})
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = request(t, "api/signin", map[string]any{"password": "secret"}, http.MethodPost, evil)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// Чтение с чужого сайта не блокируется: ответ всё равно недоступен ему без CORS
+	code, _, ret = request(t, "api/task?id="+id, nil, http.MethodGet, evil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, id, ret["id"])
+
+	// Запросы со своего сайта проходят
+	code, _, _ = request(t, "api/task?id="+id, nil, http.MethodDelete, map[string]string{"Referer": origin + "/index.html"})
+	assert.Equal(t, http.StatusOK, code)
+
+	// Кука токена не отправляется браузером в запросах с чужих сайтов
+	resp, err := http.Post(getURL("api/signin"), "application/json", strings.NewReader(`{"password": "secret"}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "token" {
+			assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+			assert.True(t, cookie.HttpOnly)
+		}
+	}
+}
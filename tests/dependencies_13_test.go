@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencies(t *testing.T) {
+	numbers := addTask(t, task{title: "Собрать цифры"})
+	report := addTask(t, task{title: "Опубликовать отчёт"})
+	review := addTask(t, task{title: "Согласовать отчёт"})
+
+	depend := func(taskID, blockedBy string) map[string]any {
+		ret, err := postJSON("api/task/dependencies", map[string]any{
+			"task_id":    taskID,
+			"blocked_by": blockedBy,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		return ret
+	}
+
+	assert.Empty(t, depend(review, numbers))
+	assert.Empty(t, depend(report, review))
+	assert.NotEmpty(t, depend(numbers, report)["error"], "Ожидается ошибка для циклической зависимости")
+	assert.NotEmpty(t, depend(numbers, numbers)["error"], "Задача не может зависеть от самой себя")
+
+	task, err := postJSON("api/task?id="+report, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, true, task["blocked"])
+	assert.Equal(t, []any{review}, task["blocked_by"])
+
+	body, err := requestJSON("api/task/dependencies?id="+review, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var graph struct {
+		Nodes []struct {
+			ID string `json:"id"`
+		} `json:"nodes"`
+		Edges []struct {
+			TaskID    string `json:"task_id"`
+			BlockedBy string `json:"blocked_by"`
+		} `json:"edges"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &graph))
+	assert.Len(t, graph.Nodes, 3)
+	assert.Len(t, graph.Edges, 2)
+
+	ret, err := postJSON("api/task/done?id="+report, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"], "Заблокированная задача не должна выполняться")
+
+	ret, err = postJSON("api/task/done?id="+numbers, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	task, err = postJSON("api/task?id="+review, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Nil(t, task["blocked"])
+
+	ret, err = postJSON("api/task/done?id="+report+"&force=true", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	ret, err = postJSON("api/task/dependencies?task_id="+report+"&blocked_by="+review, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"], "Зависимость удалённой задачи должна исчезнуть")
+
+	_, err = postJSON("api/task?id="+review, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
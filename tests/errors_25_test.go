@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorEnvelope(t *testing.T) {
+	check := func(apipath string, values map[string]any, method string, status int, code string) map[string]any {
+		got, _, ret := request(t, apipath, values, method, nil)
+		assert.Equal(t, status, got, "%s %s", method, apipath)
+		assert.NotEmpty(t, ret["error"], "%s %s", method, apipath)
+		assert.Equal(t, code, ret["code"], "%s %s", method, apipath)
+		return ret
+	}
+
+	check("api/task", nil, http.MethodGet, http.StatusBadRequest, "invalid_id")
+	check("api/task?id=999999999", nil, http.MethodGet, http.StatusNotFound, "not_found")
+	check("api/unknown", nil, http.MethodGet, http.StatusNotFound, "not_found")
+
+	ret := check("api/task", map[string]any{"date": "20240101"}, http.MethodPost, http.StatusBadRequest, "validation_failed")
+	assert.Contains(t, ret["fields"], "title")
+	ret = check("api/task", map[string]any{"date": "20240101", "title": "Задача", "repeat": "w 9"}, http.MethodPost, http.StatusBadRequest, "validation_failed")
+	assert.Contains(t, ret["fields"], "repeat")
+
+	// Ошибки отдаются как JSON, в том числе у /api/nextdate и без аутентификации
+	for _, apipath := range []string{"api/nextdate?now=bad&date=20240101&repeat=d+1", "api/tasks"} {
+		req, err := http.NewRequest(http.MethodGet, getURL(apipath), nil)
+		assert.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		if apipath == "api/tasks" && len(Token) == 0 {
+			continue
+		}
+		assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json"), apipath)
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body), apipath)
+		assert.NotEmpty(t, body["code"], apipath)
+	}
+}
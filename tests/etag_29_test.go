@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskETag(t *testing.T) {
+	ifMatch := func(etag string) map[string]string {
+		return map[string]string{"If-Match": etag}
+	}
+
+	ret, err := postJSON("api/task", map[string]any{"title": "Согласовать макет", "repeat": "d 1"}, http.MethodPost)
+	assert.NoError(t, err)
+	id, _ := ret["id"].(string)
+	assert.NotEmpty(t, id)
+	defer request(t, "api/task?id="+id, nil, http.MethodDelete, nil)
+
+	code, header, task := request(t, "api/task?id="+id, nil, http.MethodGet, nil)
+	etag := header.Get("ETag")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `"`+task["version"].(string)+`"`, etag)
+
+	update := func(title string) map[string]any {
+		return map[string]any{"id": id, "date": task["date"], "title": title, "repeat": "d 1"}
+	}
+
+	// Изменение текущей версии увеличивает её
+	code, header, _ = request(t, "api/task", update("Согласовать макет с дизайнером"), http.MethodPut, ifMatch(etag))
+	assert.Equal(t, http.StatusOK, code)
+	updated := header.Get("ETag")
+	assert.NotEqual(t, etag, updated)
+
+	// Изменение по устаревшей версии отклоняется, в ответе — текущая версия
+	code, header, ret = request(t, "api/task", update("Устаревшее изменение"), http.MethodPut, ifMatch(etag))
+	assert.Equal(t, http.StatusPreconditionFailed, code)
+	assert.Equal(t, "precondition_failed", ret["code"])
+	assert.Equal(t, updated, header.Get("ETag"))
+
+	code, _, _ = request(t, "api/task", update("Слабый ETag"), http.MethodPut, ifMatch("W/"+updated))
+	assert.Equal(t, http.StatusPreconditionFailed, code)
+
+	// Версию можно передать полем version
+	values := update("Версия в теле запроса")
+	values["version"] = task["version"]
+	code, _, _ = request(t, "api/task", values, http.MethodPut, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, code)
+
+	values["version"] = updated[1 : len(updated)-1]
+	code, header, _ = request(t, "api/task", values, http.MethodPut, nil)
+	assert.Equal(t, http.StatusOK, code)
+	updated = header.Get("ETag")
+
+	code, _, task = request(t, "api/task?id="+id, nil, http.MethodGet, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Версия в теле запроса", task["title"])
+
+	// Выполнение и удаление тоже проверяют версию
+	code, _, _ = request(t, "api/task/done?id="+id, nil, http.MethodPost, ifMatch(etag))
+	assert.Equal(t, http.StatusPreconditionFailed, code)
+	code, header, _ = request(t, "api/task/done?id="+id, nil, http.MethodPost, ifMatch(updated))
+	assert.Equal(t, http.StatusOK, code)
+	done := header.Get("ETag")
+	assert.NotEqual(t, updated, done)
+
+	code, _, _ = request(t, "api/v2/tasks/"+id, map[string]any{"comment": "Патч"}, http.MethodPatch, ifMatch(updated))
+	assert.Equal(t, http.StatusPreconditionFailed, code)
+
+	code, _, _ = request(t, "api/task?id="+id, nil, http.MethodDelete, ifMatch(updated))
+	assert.Equal(t, http.StatusPreconditionFailed, code)
+	code, _, _ = request(t, "api/task?id="+id, nil, http.MethodDelete, ifMatch(`"0", `+done))
+	assert.Equal(t, http.StatusOK, code)
+
+	// Без версии задача изменяется как раньше
+	ret, err = postJSON("api/task", map[string]any{"title": "Без версии"}, http.MethodPost)
+	assert.NoError(t, err)
+	id, _ = ret["id"].(string)
+	code, _, _ = request(t, "api/task", map[string]any{"id": id, "title": "Без версии, изменена"}, http.MethodPut, nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = request(t, "api/task?id="+id, nil, http.MethodDelete, ifMatch("*"))
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestTaskVersionRelatedChanges(t *testing.T) {
+	tag := fmt.Sprintf("версия%d", time.Now().UnixNano())
+	ret, err := postJSON("api/task", map[string]any{"title": "Проверить версию", "tags": []string{tag}}, http.MethodPost)
+	assert.NoError(t, err)
+	id, _ := ret["id"].(string)
+	assert.NotEmpty(t, id)
+	defer request(t, "api/task?id="+id, nil, http.MethodDelete, nil)
+	ret, err = postJSON("api/task", map[string]any{"title": "Блокирующая задача"}, http.MethodPost)
+	assert.NoError(t, err)
+	blocker, _ := ret["id"].(string)
+	defer request(t, "api/task?id="+blocker, nil, http.MethodDelete, nil)
+
+	// Каждое изменение связанных данных увеличивает версию задачи
+	etag := func() string {
+		code, header, _ := request(t, "api/task?id="+id, nil, http.MethodGet, nil)
+		assert.Equal(t, http.StatusOK, code)
+		return header.Get("ETag")
+	}
+	version := etag()
+	changed := func(name string, values map[string]any, path, method string) map[string]any {
+		code, _, ret := request(t, path, values, method, nil)
+		assert.Equal(t, http.StatusOK, code, name)
+		current := etag()
+		assert.NotEqual(t, version, current, name)
+		version = current
+		return ret
+	}
+
+	ret = changed("пункт чек-листа", map[string]any{"task_id": id, "title": "Первый"}, "api/task/checklist", http.MethodPost)
+	first := fmt.Sprint(ret["id"])
+	ret = changed("второй пункт", map[string]any{"task_id": id, "title": "Второй"}, "api/task/checklist", http.MethodPost)
+	second := fmt.Sprint(ret["id"])
+	changed("отметка пункта", map[string]any{"id": first, "title": "Первый", "done": true}, "api/task/checklist", http.MethodPut)
+	changed("порядок пунктов", map[string]any{"task_id": id, "ids": []string{second, first}}, "api/task/checklist/reorder", http.MethodPost)
+	changed("удаление пункта", nil, "api/task/checklist?id="+second, http.MethodDelete)
+	changed("зависимость", map[string]any{"task_id": id, "blocked_by": blocker}, "api/task/dependencies", http.MethodPost)
+	changed("удаление зависимости", nil, "api/task/dependencies?task_id="+id+"&blocked_by="+blocker, http.MethodDelete)
+
+	body, err := requestJSON("api/tags", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list struct {
+		Tags []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"tags"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &list))
+	var tagID string
+	for _, item := range list.Tags {
+		if item.Name == tag {
+			tagID = item.ID
+		}
+	}
+	assert.NotEmpty(t, tagID)
+	changed("переименование метки", map[string]any{"id": tagID, "name": tag + "новая"}, "api/tag", http.MethodPut)
+	changed("удаление метки", nil, "api/tag?id="+tagID, http.MethodDelete)
+}
@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalization(t *testing.T) {
+	english := map[string]string{"Accept-Language": "en-US,en;q=0.9,ru;q=0.8"}
+
+	code, _, ret := request(t, "api/task", nil, http.MethodGet, english)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "id: Field is required", ret["error"])
+	assert.Equal(t, "invalid_id", ret["code"])
+
+	code, _, ret = request(t, "api/task", nil, http.MethodGet, map[string]string{"Accept-Language": "de, ru;q=0.5"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "id: Обязательное поле", ret["error"])
+
+	// Ошибки правил повторения переводятся вместе с параметрами
+	code, header, ret := request(t, "api/nextdate?now=20240126&date=20240126&repeat=w+9", nil, http.MethodGet, english)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "en", header.Get("Content-Language"))
+	assert.Equal(t, "Invalid format: 9", ret["error"])
+
+	// Язык из профиля важнее заголовка Accept-Language
+	code, _, ret = request(t, "api/profile", map[string]any{"language": "xx"}, http.MethodPut,
+		map[string]string{"Accept-Language": "en"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Language is not supported", ret["error"])
+	assert.Contains(t, ret["fields"], "language")
+
+	code, _, ret = request(t, "api/profile", map[string]any{"language": "en"}, http.MethodPut, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "en", ret["language"])
+	defer request(t, "api/profile", map[string]any{"language": ""}, http.MethodPut, nil)
+
+	code, _, ret = request(t, "api/task", nil, http.MethodGet, map[string]string{"Accept-Language": "ru"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "id: Field is required", ret["error"])
+
+	code, _, ret = request(t, "api/profile", nil, http.MethodGet, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "en", ret["language"])
+}
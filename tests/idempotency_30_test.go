@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	key := fmt.Sprintf("create-%d", time.Now().UnixNano())
+	task := map[string]any{"title": "Оплатить счёт", "repeat": "d 2"}
+
+	before, err := count(db)
+	assert.NoError(t, err)
+
+	// Повтор запроса возвращает первый ответ и не создаёт задачу снова
+	code, header, ret := request(t, "api/task", task, http.MethodPost, map[string]string{"Idempotency-Key": key})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, header.Get("Idempotent-Replayed"))
+	id, _ := ret["id"].(string)
+	assert.NotEmpty(t, id)
+	defer request(t, "api/task?id="+id, nil, http.MethodDelete, nil)
+
+	code, header, ret = request(t, "api/task", task, http.MethodPost, map[string]string{"Idempotency-Key": key})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "true", header.Get("Idempotent-Replayed"))
+	assert.Equal(t, id, ret["id"])
+
+	after, err := count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, before+1, after)
+
+	// Тот же ключ нельзя использовать для другого запроса
+	code, _, ret = request(t, "api/task", map[string]any{"title": "Другая задача"}, http.MethodPost, map[string]string{"Idempotency-Key": key})
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "idempotency_key_reused", ret["code"])
+
+	// Повтор выполнения переносит повторяющуюся задачу только один раз
+	var date string
+	assert.NoError(t, db.Get(&date, `SELECT date FROM scheduler WHERE id = ?`, id))
+
+	doneKey := fmt.Sprintf("done-%d", time.Now().UnixNano())
+	for i := 0; i < 3; i++ {
+		code, _, _ = request(t, "api/task/done?id="+id, nil, http.MethodPost, map[string]string{"Idempotency-Key": doneKey})
+		assert.Equal(t, http.StatusOK, code)
+	}
+
+	var next string
+	assert.NoError(t, db.Get(&next, `SELECT date FROM scheduler WHERE id = ?`, id))
+	start, err := time.Parse("20060102", date)
+	assert.NoError(t, err)
+	assert.Equal(t, start.AddDate(0, 0, 2).Format("20060102"), next)
+
+	// Запрос с новым ключом выполняется заново
+	code, header, _ = request(t, "api/task/done?id="+id, nil, http.MethodPost, map[string]string{"Idempotency-Key": doneKey + "-2"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, header.Get("Idempotent-Replayed"))
+	assert.NoError(t, db.Get(&next, `SELECT date FROM scheduler WHERE id = ?`, id))
+	assert.Equal(t, start.AddDate(0, 0, 4).Format("20060102"), next)
+}
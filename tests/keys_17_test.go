@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// Возвращаем идентификатор ключа из заголовка токена
+func tokenKeyID(t *testing.T, token string) string {
+	header, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(header, &m))
+	return fmt.Sprint(m["kid"])
+}
+
+func TestKeyRotation(t *testing.T) {
+	password := "secret-password"
+	login := addUser(t, password)
+
+	signin := func() string {
+		ret, err := postJSON("api/signin", map[string]any{"login": login, "password": password}, http.MethodPost)
+		assert.NoError(t, err)
+		token := fmt.Sprint(ret["token"])
+		assert.NotEmpty(t, token)
+		return token
+	}
+
+	before := signin()
+	assert.NotEmpty(t, tokenKeyID(t, before))
+
+	ret, err := postJSON("api/admin/keys/rotate", nil, http.MethodPost)
+	assert.NoError(t, err)
+	kid := fmt.Sprint(ret["kid"])
+	assert.NotEmpty(t, kid)
+	assert.NotEqual(t, tokenKeyID(t, before), kid)
+
+	after := signin()
+	assert.Equal(t, kid, tokenKeyID(t, after))
+
+	if len(Token) == 0 {
+		return
+	}
+
+	// Токены, подписанные прежним ключом, действуют до истечения срока
+	code, _, _ := request(t, "api/tasks", nil, http.MethodGet, asUser(before))
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = request(t, "api/tasks", nil, http.MethodGet, asUser(after))
+	assert.Equal(t, http.StatusOK, code)
+
+	// Токены с неизвестным ключом или без kid отклоняются
+	claims := jwt.MapClaims{"authorized": true, "uid": 1, "exp": time.Now().Add(time.Hour).Unix()}
+	for _, kid := range []any{kid, nil} {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		if kid != nil {
+			token.Header["kid"] = kid
+		}
+		forged, err := token.SignedString([]byte("guessed-secret"))
+		assert.NoError(t, err)
+		code, _, _ = request(t, "api/tasks", nil, http.MethodGet, asUser(forged))
+		assert.Equal(t, http.StatusUnauthorized, code)
+	}
+}
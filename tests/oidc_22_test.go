@@ -0,0 +1,331 @@
+package tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// Учётные данные клиента, с которыми должен быть запущен сервер
+const (
+	oidcClientID     = "todo-test"
+	oidcClientSecret = "test-secret"
+)
+
+// Вход, разрешённый пользователем на странице провайдера
+type mockGrant struct {
+	challenge string
+	nonce     string
+	sub       string
+	login     string
+}
+
+// Провайдер OIDC для тестов: discovery, JWKS и выдача ID токенов, подписанных RS256
+type mockOIDC struct {
+	t      *testing.T
+	issuer string
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	grants map[string]mockGrant
+}
+
+func startMockOIDC(t *testing.T, port int) (*mockOIDC, func()) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	m := &mockOIDC{t: t, issuer: fmt.Sprintf("http://localhost:%d", port), key: key, grants: map[string]mockGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.issuer,
+			"authorization_endpoint": m.issuer + "/authorize",
+			"token_endpoint":         m.issuer + "/token",
+			"jwks_uri":               m.issuer + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		key := m.key
+		m.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", m.token)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	assert.NoError(t, err)
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	return m, func() { server.Close() }
+}
+
+// Меняем ключ подписи, оставляя прежний kid
+func (m *mockOIDC) rotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(m.t, err)
+	m.mu.Lock()
+	m.key = key
+	m.mu.Unlock()
+}
+
+// Обмениваем код на ID токен, проверяя клиента и code_verifier
+func (m *mockOIDC) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	m.mu.Lock()
+	grant, ok := m.grants[r.Form.Get("code")]
+	delete(m.grants, r.Form.Get("code"))
+	key := m.key
+	m.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if !ok || r.Form.Get("grant_type") != "authorization_code" ||
+		r.Form.Get("client_id") != oidcClientID || r.Form.Get("client_secret") != oidcClientSecret ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                m.issuer,
+		"aud":                []string{oidcClientID},
+		"sub":                grant.sub,
+		"nonce":              grant.nonce,
+		"preferred_username": grant.login,
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(5 * time.Minute).Unix(),
+	})
+	token.Header["kid"] = "test-key"
+	idToken, err := token.SignedString(key)
+	assert.NoError(m.t, err)
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "unused", "token_type": "Bearer", "id_token": idToken})
+}
+
+var noRedirects = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}}
+
+// Проходим вход через провайдера. grant позволяет изменить ответ провайдера,
+// а callback — запрос возврата на сервер
+func (m *mockOIDC) signin(sub, login string, grant func(*mockGrant), callback func(url.Values, *http.Cookie)) (*http.Response, *http.Cookie) {
+	resp, err := noRedirects.Get(getURL("api/oidc/login"))
+	assert.NoError(m.t, err)
+	resp.Body.Close()
+	assert.Equal(m.t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	assert.NoError(m.t, err)
+	assert.Equal(m.t, m.issuer+"/authorize", location.Scheme+"://"+location.Host+location.Path)
+	params := location.Query()
+	assert.Equal(m.t, "code", params.Get("response_type"))
+	assert.Equal(m.t, oidcClientID, params.Get("client_id"))
+	assert.Equal(m.t, "S256", params.Get("code_challenge_method"))
+	assert.NotEmpty(m.t, params.Get("nonce"))
+
+	var stateCookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "oidc_state" {
+			stateCookie = c
+		}
+	}
+	assert.NotNil(m.t, stateCookie)
+
+	code := fmt.Sprintf("code%d", time.Now().UnixNano())
+	g := mockGrant{challenge: params.Get("code_challenge"), nonce: params.Get("nonce"), sub: sub, login: login}
+	if grant != nil {
+		grant(&g)
+	}
+	m.mu.Lock()
+	m.grants[code] = g
+	m.mu.Unlock()
+
+	query := url.Values{"code": {code}, "state": {params.Get("state")}}
+	if callback != nil {
+		callback(query, stateCookie)
+	}
+	req, err := http.NewRequest(http.MethodGet, params.Get("redirect_uri")+"?"+query.Encode(), nil)
+	assert.NoError(m.t, err)
+	req.AddCookie(stateCookie)
+	resp, err = noRedirects.Do(req)
+	assert.NoError(m.t, err)
+	resp.Body.Close()
+
+	for _, c := range resp.Cookies() {
+		if c.Name == "token" {
+			return resp, c
+		}
+	}
+	return resp, nil
+}
+
+// Находим пользователя в списке администратора
+func findUser(t *testing.T, login string) map[string]any {
+	code, _, ret := request(t, "api/admin/users", nil, http.MethodGet, asUser(Token))
+	assert.Equal(t, http.StatusOK, code)
+	users, _ := ret["users"].([]any)
+	for _, u := range users {
+		if user := u.(map[string]any); user["login"] == login {
+			return user
+		}
+	}
+	return nil
+}
+
+func TestOIDC(t *testing.T) {
+	// Адрес провайдера сервер получает при запуске, поэтому порт провайдера задаётся заранее
+	if OIDCPort == 0 {
+		t.Skip("требуется OIDCPort и сервер, запущенный с TODO_OIDC_ISSUER=http://localhost:<OIDCPort>")
+	}
+	m, stop := startMockOIDC(t, OIDCPort)
+	defer stop()
+
+	sub := fmt.Sprintf("sub%d", time.Now().UnixNano())
+	login := fmt.Sprintf("sso%d", time.Now().UnixNano())
+
+	// Новый пользователь создаётся при первом входе
+	resp, token := m.signin(sub, login, nil, nil)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/", resp.Header.Get("Location"))
+	if assert.NotNil(t, token) {
+		code, _, _ := request(t, "api/tasks", nil, http.MethodGet, asUser(token.Value))
+		assert.Equal(t, http.StatusOK, code)
+	}
+
+	// Повторный вход находит того же пользователя по sub, даже если логин у провайдера изменился
+	resp, token = m.signin(sub, login+"-renamed", nil, nil)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.NotNil(t, token)
+
+	// Существующий пользователь не связывается по логину: утверждение может изменить сам пользователь
+	existing := addUser(t, "secret-password")
+	resp, token = m.signin(sub+"-existing", existing, nil, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Nil(t, token)
+	user := findUser(t, existing)
+	assert.Nil(t, user["oidc_subject"])
+	resp, token = m.signin(sub+"-admin", "admin", nil, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Nil(t, token)
+
+	// Связь с учётной записью провайдера задаёт администратор
+	subject := m.issuer + "|" + sub + "-existing"
+	code, _, _ := request(t, "api/admin/users", map[string]any{"id": user["id"], "oidc_subject": subject}, http.MethodPut, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, subject, findUser(t, existing)["oidc_subject"])
+	resp, token = m.signin(sub+"-existing", "other-login", nil, nil)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	if assert.NotNil(t, token) && len(Token) > 0 {
+		code, _, ret := request(t, "api/profile", nil, http.MethodGet, asUser(token.Value))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, existing, ret["login"])
+	}
+
+	// Учётную запись провайдера можно связать только с одним пользователем
+	other := findUser(t, addUser(t, "secret-password"))
+	code, _, ret := request(t, "api/admin/users", map[string]any{"id": other["id"], "oidc_subject": subject}, http.MethodPut, nil)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.NotEmpty(t, ret["error"])
+	code, _, ret = request(t, "api/admin/users", map[string]any{"id": other["id"], "oidc_subject": sub}, http.MethodPut, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, ret["fields"], "oidc_subject")
+
+	// Без связи пользователь больше не входит через провайдера
+	code, _, _ = request(t, "api/admin/users", map[string]any{"id": user["id"], "oidc_subject": ""}, http.MethodPut, nil)
+	assert.Equal(t, http.StatusOK, code)
+	resp, token = m.signin(sub+"-existing", existing, nil, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Nil(t, token)
+
+	// Со вторым фактором сессия открывается только после проверки кода, как и при входе по паролю
+	if len(Token) > 0 {
+		secured := addUser(t, "secret-password")
+		ret, err := postJSON("api/signin", map[string]any{"login": secured, "password": "secret-password"}, http.MethodPost)
+		assert.NoError(t, err)
+		secret, step, _ := enableTOTP(t, fmt.Sprint(ret["token"]))
+		code, _, _ = request(t, "api/admin/users", map[string]any{
+			"id": findUser(t, secured)["id"], "oidc_subject": m.issuer + "|" + sub + "-2fa",
+		}, http.MethodPut, nil)
+		assert.Equal(t, http.StatusOK, code)
+
+		resp, token = m.signin(sub+"-2fa", secured, nil, nil)
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.Equal(t, "/login.html?two_factor=oidc", resp.Header.Get("Location"))
+		assert.Nil(t, token)
+		var challenge string
+		for _, c := range resp.Cookies() {
+			if c.Name == "oidc_challenge" {
+				challenge = c.Value
+			}
+		}
+		assert.NotEmpty(t, challenge)
+		cookie := map[string]string{"Cookie": "oidc_challenge=" + challenge}
+
+		code, _, ret = request(t, "api/oidc/2fa", map[string]any{"code": ""}, http.MethodPost, cookie)
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.Equal(t, "two_factor_required", ret["code"])
+		code, _, ret = request(t, "api/oidc/2fa", map[string]any{"code": "abcdef"}, http.MethodPost, cookie)
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.Equal(t, "invalid_two_factor_code", ret["code"])
+		code, _, _ = request(t, "api/oidc/2fa", map[string]any{"code": totpCode(t, secret, step+1)}, http.MethodPost,
+			map[string]string{"Cookie": "oidc_challenge=forged"})
+		assert.Equal(t, http.StatusUnauthorized, code)
+
+		code, _, ret = request(t, "api/oidc/2fa", map[string]any{"code": totpCode(t, secret, step+1)}, http.MethodPost, cookie)
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotEmpty(t, ret["token"])
+		code, _, ret = request(t, "api/profile", nil, http.MethodGet, asUser(fmt.Sprint(ret["token"])))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, secured, ret["login"])
+
+		// Завершённый вход нельзя использовать повторно
+		code, _, _ = request(t, "api/oidc/2fa", map[string]any{"code": totpCode(t, secret, step+2)}, http.MethodPost, cookie)
+		assert.Equal(t, http.StatusUnauthorized, code)
+	}
+
+	// ID токен с чужим nonce отклоняется
+	resp, token = m.signin(sub, login, func(g *mockGrant) { g.nonce = "replayed" }, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Nil(t, token)
+
+	// Ответ провайдера должен прийти в тот же браузер, который начал вход
+	resp, token = m.signin(sub, login, nil, func(_ url.Values, c *http.Cookie) { c.Value = "other" })
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Nil(t, token)
+	resp, token = m.signin(sub, login, nil, func(q url.Values, c *http.Cookie) {
+		q.Set("state", "forged")
+		c.Value = "forged"
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Nil(t, token)
+
+	// Код, выданный без подтверждения через PKCE, провайдер не обменивает
+	resp, token = m.signin(sub, login, func(g *mockGrant) { g.challenge = "other" }, nil)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Nil(t, token)
+
+	// Провайдер сменил ключ, оставив прежний kid: при несовпадении подписи ключи перезагружаются.
+	// Перезагрузка выполняется не чаще раза в 5 секунд
+	m.rotateKey()
+	time.Sleep(5 * time.Second)
+	resp, token = m.signin(sub, login, nil, nil)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.NotNil(t, token)
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"todo-app/router"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// Получаем спецификацию, которую отдаёт приложение
+func getSpec(t *testing.T) map[string]map[string]any {
+	body, err := getBody("api/openapi.json")
+	assert.NoError(t, err)
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &spec))
+	assert.True(t, strings.HasPrefix(spec.OpenAPI, "3."), "Ожидается спецификация OpenAPI 3")
+	return spec.Paths
+}
+
+// Шаблон маршрута без регулярных выражений: /api/v2/tasks/{id:[0-9]+} -> /api/v2/tasks/{id}
+var routeVariable = regexp.MustCompile(`\{(\w+):[^}]*\}`)
+
+// Спецификация и роутер описывают одни и те же адреса и методы
+func TestOpenAPISpecMatchesRouter(t *testing.T) {
+	paths := getSpec(t)
+	for _, path := range []string{"/api/signin", "/api/nextdate", "/api/task", "/api/task/done", "/api/tasks"} {
+		assert.Contains(t, paths, path)
+	}
+
+	r := router.NewRouter()
+	for path, operations := range paths {
+		for method := range operations {
+			method = strings.ToUpper(method)
+			req := httptest.NewRequest(method, strings.ReplaceAll(path, "{id}", "1"), nil)
+
+			var match mux.RouteMatch
+			if !assert.True(t, r.Match(req, &match), "%s %s нет в роутере", method, path) {
+				continue
+			}
+			template, _ := match.Route.GetPathTemplate()
+			assert.Equal(t, path, routeVariable.ReplaceAllString(template, "{$1}"), "%s %s нет в роутере", method, path)
+		}
+	}
+
+	// Все методы описанных адресов есть в спецификации
+	r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		operations, ok := paths[routeVariable.ReplaceAllString(template, "{$1}")]
+		if !ok {
+			return nil
+		}
+		methods, _ := route.GetMethods()
+		for _, method := range methods {
+			assert.Contains(t, operations, strings.ToLower(method), "%s %s нет в спецификации", method, template)
+		}
+		return nil
+	})
+}
+
+func TestOpenAPIValidation(t *testing.T) {
+	code, _, ret := request(t, "api/task", map[string]any{"title": 5}, http.MethodPost, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "validation_failed", ret["code"])
+	assert.Contains(t, ret["fields"], "title")
+
+	code, _, ret = request(t, "api/task", map[string]any{"title": "Задача", "priority": 7}, http.MethodPost, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, ret["fields"], "priority")
+
+	code, _, ret = request(t, "api/tasks?limit=много", nil, http.MethodGet, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, ret["fields"], "limit")
+
+	code, _, ret = request(t, "api/nextdate?now=bad&date=20240101&repeat=d+1", nil, http.MethodGet, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, ret["fields"], "now")
+
+	code, _, ret = request(t, "api/task?id=abc", nil, http.MethodGet, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "invalid_id", ret["code"])
+
+	ret, err := postJSON("api/task", map[string]any{"title": "Проверка по спецификации"}, http.MethodPost)
+	assert.NoError(t, err)
+	id, _ := ret["id"].(string)
+	assert.NotEmpty(t, id)
+	defer request(t, "api/task?id="+id, nil, http.MethodDelete, nil)
+
+	code, _, ret = request(t, "api/v2/tasks/"+id, map[string]any{"foo": 1}, http.MethodPatch,
+		map[string]string{"Content-Type": "application/merge-patch+json"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "foo: Неизвестное поле", ret["error"])
+	assert.Contains(t, ret["fields"], "foo")
+}
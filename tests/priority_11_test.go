@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriority(t *testing.T) {
+	day := time.Now().AddDate(1, 2, 3)
+	nextDay := day.AddDate(0, 0, 1)
+
+	for _, priority := range []int{-1, 5} {
+		ret, err := postJSON("api/task", map[string]any{
+			"date":     day.Format(`20060102`),
+			"title":    "Неверный приоритет",
+			"priority": priority,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], "Ожидается ошибка для приоритета %d", priority)
+	}
+
+	add := func(date time.Time, title string, priority int) string {
+		ret, err := postJSON("api/task", map[string]any{
+			"date":     date.Format(`20060102`),
+			"title":    title,
+			"priority": priority,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		return fmt.Sprint(ret["id"])
+	}
+
+	none := add(day, "Без приоритета", 0)
+	low := add(day, "Низкий приоритет", 4)
+	urgent := add(day, "Срочно", 1)
+	later := add(nextDay, "Срочно, но завтра", 1)
+
+	ids := getTaskIDs(t, "search="+day.Format(`02.01.2006`))
+	assert.Equal(t, []string{urgent, low, none}, ids)
+
+	// Без поля priority приоритет задачи не меняется
+	ret, err := postJSON("api/task", map[string]any{
+		"id":    low,
+		"date":  day.Format(`20060102`),
+		"title": "Низкий приоритет",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	task, err := postJSON("api/task?id="+low, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, task["priority"])
+
+	var ordered []string
+	for _, id := range getTaskIDs(t, "sort=priority") {
+		switch id {
+		case none, low, urgent, later:
+			ordered = append(ordered, id)
+		}
+	}
+	assert.Equal(t, []string{urgent, later, low, none}, ordered)
+
+	ret, err = postJSON("api/tasks?sort=title", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	for _, id := range []string{none, low, urgent, later} {
+		_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addProject(t *testing.T, name, color string) string {
+	ret, err := postJSON("api/project", map[string]any{
+		"name":  name,
+		"color": color,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["id"], "Не создан проект %s: %v", name, ret["error"])
+	return fmt.Sprint(ret["id"])
+}
+
+func TestProjects(t *testing.T) {
+	now := time.Now().Format(`20060102`)
+
+	ret, err := postJSON("api/project", map[string]any{"name": "Цвет", "color": "red"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"], "Ожидается ошибка для некорректного цвета")
+
+	work := addProject(t, "Работа", "#ff0000")
+	home := addProject(t, "Дом", "#00ff00")
+
+	ret, err = postJSON("api/task", map[string]any{
+		"date":       now,
+		"title":      "Подготовить релиз",
+		"project_id": work,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	task, err := postJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, work, task["project_id"])
+	assert.Contains(t, getTaskIDs(t, "project_id="+work), id)
+
+	// Редактирование без project_id оставляет задачу в проекте
+	ret, err = postJSON("api/task", map[string]any{
+		"id":    id,
+		"date":  now,
+		"title": "Подготовить релиз 1.0",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Contains(t, getTaskIDs(t, "project_id="+work), id)
+
+	ret, err = postJSON("api/task/move?id="+id+"&project_id="+home, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NotContains(t, getTaskIDs(t, "project_id="+work), id)
+	assert.Contains(t, getTaskIDs(t, "project_id="+home), id)
+
+	ret, err = postJSON("api/projects/reorder", map[string]any{"ids": []string{home, work}}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	ret, err = postJSON("api/project/archive?id="+home, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NotContains(t, getTaskIDs(t, ""), id, "Задачи архивного проекта не должны попадать в общий список")
+	assert.Contains(t, getTaskIDs(t, "project_id="+home), id)
+
+	body, err := requestJSON("api/projects", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list struct {
+		Projects []struct {
+			ID string `json:"id"`
+		} `json:"projects"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &list))
+	for _, project := range list.Projects {
+		assert.NotEqual(t, home, project.ID, "Архивный проект не должен попадать в список")
+	}
+
+	ret, err = postJSON("api/task/move?id="+id+"&project_id="+home, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"], "Нельзя переносить задачи в архивный проект")
+
+	for _, project := range []string{work, home} {
+		ret, err = postJSON("api/project?id="+project, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	task, err = postJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Nil(t, task["project_id"])
+
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoles(t *testing.T) {
+	password := "secret-password"
+	login := fmt.Sprintf("wall%d", time.Now().UnixNano())
+	now := time.Now().Format(`20060102`)
+
+	ret, err := postJSON("api/admin/users", map[string]any{"login": login, "password": password, "role": "guest"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	ret, err = postJSON("api/admin/users", map[string]any{"login": login, "password": password, "role": "readonly"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	id := fmt.Sprint(ret["id"])
+	assert.Equal(t, "readonly", findUser(t, login)["role"])
+
+	// Встроенного администратора нельзя понизить или удалить
+	admin := findUser(t, "admin")
+	if admin != nil {
+		code, _, _ := request(t, "api/admin/users", map[string]any{"id": admin["id"], "role": "member"}, http.MethodPut, asUser(Token))
+		assert.Equal(t, http.StatusForbidden, code)
+		code, _, _ = request(t, "api/admin/users?id="+fmt.Sprint(admin["id"]), nil, http.MethodDelete, asUser(Token))
+		assert.Equal(t, http.StatusForbidden, code)
+	}
+
+	if len(Token) > 0 {
+		ret, err = postJSON("api/signin", map[string]any{"login": login, "password": password}, http.MethodPost)
+		assert.NoError(t, err)
+		token := fmt.Sprint(ret["token"])
+
+		// Пользователь только для чтения видит списки, но ничего не меняет
+		code, _, _ := request(t, "api/tasks", nil, http.MethodGet, asUser(token))
+		assert.Equal(t, http.StatusOK, code)
+		code, _, _ = request(t, "api/task", map[string]any{"date": now, "title": "Нельзя"}, http.MethodPost, asUser(token))
+		assert.Equal(t, http.StatusForbidden, code)
+		code, _, _ = request(t, "api/admin/users", nil, http.MethodGet, asUser(token))
+		assert.Equal(t, http.StatusForbidden, code)
+		code, _, _ = request(t, "api/tokens", map[string]any{"name": "x", "scopes": []string{"tasks:write"}}, http.MethodPost, asUser(token))
+		assert.Equal(t, http.StatusForbidden, code)
+		code, _, _ = request(t, "api/tokens", map[string]any{"name": "x", "scopes": []string{"read-only"}}, http.MethodPost, asUser(token))
+		assert.Equal(t, http.StatusOK, code)
+
+		// Новая роль действует сразу
+		code, _, _ = request(t, "api/admin/users", map[string]any{"id": id, "role": "member"}, http.MethodPut, asUser(Token))
+		assert.Equal(t, http.StatusOK, code)
+		code, _, _ = request(t, "api/task", map[string]any{"date": now, "title": "Можно"}, http.MethodPost, asUser(token))
+		assert.Equal(t, http.StatusOK, code)
+
+		// Смена пароля закрывает сессии пользователя
+		code, _, _ = request(t, "api/admin/users", map[string]any{"id": id, "password": "short"}, http.MethodPut, asUser(Token))
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, _, _ = request(t, "api/admin/users", map[string]any{"id": id, "password": "new-password"}, http.MethodPut, asUser(Token))
+		assert.Equal(t, http.StatusOK, code)
+		code, _, _ = request(t, "api/tasks", nil, http.MethodGet, asUser(token))
+		assert.Equal(t, http.StatusUnauthorized, code)
+		ret, err = postJSON("api/signin", map[string]any{"login": login, "password": "new-password"}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["token"])
+		token = fmt.Sprint(ret["token"])
+
+		// Вместе с пользователем удаляются и его ключи идемпотентности
+		headers := asUser(token)
+		headers["Idempotency-Key"] = fmt.Sprintf("roles-%d", time.Now().UnixNano())
+		code, _, _ = request(t, "api/task", map[string]any{"date": now, "title": "Однажды"}, http.MethodPost, headers)
+		assert.Equal(t, http.StatusOK, code)
+
+		code, _, _ = request(t, "api/admin/users?id="+id, nil, http.MethodDelete, asUser(Token))
+		assert.Equal(t, http.StatusOK, code)
+		code, _, _ = request(t, "api/tasks", nil, http.MethodGet, asUser(token))
+		assert.Equal(t, http.StatusUnauthorized, code)
+
+		db := openDB(t)
+		defer db.Close()
+		var keys int
+		assert.NoError(t, db.Get(&keys, `SELECT COUNT(*) FROM idempotency_keys WHERE user_id = ?`, id))
+		assert.Equal(t, 0, keys)
+	} else {
+		code, _, _ := request(t, "api/admin/users?id="+id, nil, http.MethodDelete, asUser(Token))
+		assert.Equal(t, http.StatusOK, code)
+	}
+
+	assert.Nil(t, findUser(t, login))
+	code, _, _ := request(t, "api/admin/users?id="+id, nil, http.MethodDelete, asUser(Token))
+	assert.Equal(t, http.StatusNotFound, code)
+}
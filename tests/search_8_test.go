@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func containsTask(tasks []map[string]string, id string) bool {
+	for _, task := range tasks {
+		if task["id"] == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSearchUnicode(t *testing.T) {
+	if !Search {
+		return
+	}
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	milk := addTask(t, task{
+		date:    now.Format(`20060102`),
+		title:   "Купить молоко",
+		comment: "И ещё ЁЛОЧНЫЕ игрушки",
+	})
+	meeting := addTask(t, task{
+		date:  now.Format(`20060102`),
+		title: "Prepare Meetings agenda",
+	})
+
+	tbl := []struct {
+		search string
+		id     string
+	}{
+		{"купить", milk},
+		{"КУПИТЬ МОЛОКО", milk},
+		{"елочные", milk},
+		{"meetings", meeting},
+	}
+	// Формы слов находятся, только если сервер запущен с TODO_SEARCH_STEMMING=true
+	if SearchStemming {
+		tbl = append(tbl, []struct {
+			search string
+			id     string
+		}{
+			{"молока", milk},
+			{"meeting", meeting},
+			{"PREPARING", meeting},
+		}...)
+	}
+	for _, v := range tbl {
+		tasks := getTasks(t, url.QueryEscape(v.search))
+		assert.True(t, containsTask(tasks, v.id), "Задача %s не найдена по запросу %q", v.id, v.search)
+	}
+
+	tasks := getTasks(t, url.QueryEscape("купить хлеб"))
+	assert.False(t, containsTask(tasks, milk), "Все слова запроса должны встречаться в задаче")
+}
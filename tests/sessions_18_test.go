@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessions(t *testing.T) {
+	password := "secret-password"
+	login := addUser(t, password)
+
+	signin := func() (string, string) {
+		ret, err := postJSON("api/signin", map[string]any{"login": login, "password": password}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["token"])
+		assert.NotEmpty(t, ret["refresh_token"])
+		return fmt.Sprint(ret["token"]), fmt.Sprint(ret["refresh_token"])
+	}
+	refresh := func(refreshToken string) map[string]any {
+		ret, err := postJSON("api/token/refresh", map[string]any{"refresh_token": refreshToken}, http.MethodPost)
+		assert.NoError(t, err)
+		return ret
+	}
+
+	token, refreshToken := signin()
+
+	// Refresh-токен одноразовый: взамен выдаётся новый
+	ret := refresh(refreshToken)
+	assert.Empty(t, ret["error"])
+	assert.NotEmpty(t, ret["token"])
+	newRefresh := fmt.Sprint(ret["refresh_token"])
+	assert.NotEqual(t, refreshToken, newRefresh)
+	refreshed := fmt.Sprint(ret["token"])
+
+	// Повторное использование старого refresh-токена закрывает сессию
+	ret = refresh(refreshToken)
+	assert.NotEmpty(t, ret["error"])
+	ret = refresh(newRefresh)
+	assert.NotEmpty(t, ret["error"])
+
+	ret = refresh("bogus")
+	assert.NotEmpty(t, ret["error"])
+
+	if len(Token) == 0 {
+		return
+	}
+
+	// Токены закрытой сессии больше не действуют
+	code, _, _ := request(t, "api/tasks", nil, http.MethodGet, asUser(token))
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _, _ = request(t, "api/tasks", nil, http.MethodGet, asUser(refreshed))
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	first, _ := signin()
+	second, _ := signin()
+
+	code, _, ret = request(t, "api/sessions", nil, http.MethodGet, asUser(first))
+	assert.Equal(t, http.StatusOK, code)
+	sessions, _ := ret["sessions"].([]any)
+	assert.Len(t, sessions, 2)
+	var secondID string
+	for _, s := range sessions {
+		session := s.(map[string]any)
+		assert.NotEmpty(t, session["ip"])
+		assert.NotEmpty(t, session["last_seen"])
+		if session["current"] != true {
+			secondID = fmt.Sprint(session["id"])
+		}
+	}
+	assert.NotEmpty(t, secondID)
+
+	// Отзыв другой сессии
+	code, _, _ = request(t, "api/sessions?id="+secondID, nil, http.MethodDelete, asUser(first))
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = request(t, "api/tasks", nil, http.MethodGet, asUser(second))
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// Отозванная сессия больше не находится
+	code, _, _ = request(t, "api/sessions?id="+secondID, nil, http.MethodDelete, asUser(first))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Выход закрывает текущую сессию
+	code, _, _ = request(t, "api/signout", nil, http.MethodPost, asUser(first))
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = request(t, "api/tasks", nil, http.MethodGet, asUser(first))
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
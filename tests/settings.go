@@ -4,4 +4,6 @@ var Port = 7540
 var DBFile = "../scheduler.db"
 var FullNextDate = true
 var Search = true
+var SearchStemming = false
 var Token = ``
+var OIDCPort = 0
@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Регистрируем пользователя и возвращаем его логин
+func addUser(t *testing.T, password string) string {
+	login := fmt.Sprintf("user%d", time.Now().UnixNano())
+	ret, err := postJSON("api/admin/users", map[string]any{
+		"login":    login,
+		"password": password,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	return login
+}
+
+func TestSharing(t *testing.T) {
+	password := "secret-password"
+	viewer := addUser(t, password)
+	editor := addUser(t, password)
+	now := time.Now().Format(`20060102`)
+
+	ret, err := postJSON("api/task", map[string]any{"date": now, "title": "Общая задача"}, http.MethodPost)
+	assert.NoError(t, err)
+	task := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/project", map[string]any{"name": "Семья"}, http.MethodPost)
+	assert.NoError(t, err)
+	project := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task", map[string]any{"date": now, "title": "Купить хлеб", "project_id": project}, http.MethodPost)
+	assert.NoError(t, err)
+	projectTask := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/share", map[string]any{"task_id": task, "login": viewer, "permission": "viewer"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.NotEmpty(t, ret["id"])
+
+	ret, err = postJSON("api/share", map[string]any{"project_id": project, "login": editor, "permission": "editor"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	projectShare := fmt.Sprint(ret["id"])
+
+	// Некорректные запросы
+	for _, values := range []map[string]any{
+		{"task_id": task, "login": viewer, "permission": "owner"},
+		{"task_id": task, "project_id": project, "login": viewer},
+		{"login": viewer},
+		{"task_id": task, "login": "нет-такого-пользователя"},
+		{"task_id": "999999999", "login": viewer},
+	} {
+		ret, err = postJSON("api/share", values, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], "ожидается ошибка для %v", values)
+	}
+
+	body, err := requestJSON("api/share?task_id="+task, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &list))
+	assert.Len(t, list["shares"], 1)
+	if len(list["shares"]) == 1 {
+		assert.Equal(t, viewer, list["shares"][0]["login"])
+		assert.Equal(t, "viewer", list["shares"][0]["permission"])
+	}
+
+	// Проверка прав выполняется только при включённой аутентификации
+	if len(Token) > 0 {
+		signin := func(login string) string {
+			ret, err := postJSON("api/signin", map[string]any{"login": login, "password": password}, http.MethodPost)
+			assert.NoError(t, err)
+			return fmt.Sprint(ret["token"])
+		}
+		viewerToken := signin(viewer)
+		editorToken := signin(editor)
+
+		code, _, ret := request(t, "api/shared", nil, http.MethodGet, asUser(viewerToken))
+		assert.Equal(t, http.StatusOK, code)
+		tasks, _ := ret["tasks"].([]any)
+		assert.Len(t, tasks, 1)
+
+		code, _, ret = request(t, "api/task?id="+task, nil, http.MethodGet, asUser(viewerToken))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "viewer", ret["permission"])
+
+		code, _, _ = request(t, "api/task", map[string]any{
+			"id": task, "date": now, "title": "Изменено", "comment": "", "repeat": "",
+		}, http.MethodPut, asUser(viewerToken))
+		assert.Equal(t, http.StatusForbidden, code)
+		code, _, _ = request(t, "api/task/done?id="+task, nil, http.MethodPost, asUser(viewerToken))
+		assert.Equal(t, http.StatusForbidden, code)
+		code, _, _ = request(t, "api/task?id="+task, nil, http.MethodDelete, asUser(viewerToken))
+		assert.Equal(t, http.StatusForbidden, code)
+		code, _, _ = request(t, "api/task?id="+projectTask, nil, http.MethodGet, asUser(viewerToken))
+		assert.Equal(t, http.StatusNotFound, code)
+
+		// Недоступные блокирующие задачи не раскрываются, но задача остаётся заблокированной
+		ret, err = postJSON("api/task/dependencies", map[string]any{"task_id": task, "blocked_by": projectTask}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+		code, _, ret = request(t, "api/task?id="+task, nil, http.MethodGet, asUser(viewerToken))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, true, ret["blocked"])
+		assert.Nil(t, ret["blocked_by"])
+		code, _, ret = request(t, "api/shared", nil, http.MethodGet, asUser(viewerToken))
+		assert.Equal(t, http.StatusOK, code)
+		if tasks, _ := ret["tasks"].([]any); assert.Len(t, tasks, 1) {
+			assert.Nil(t, tasks[0].(map[string]any)["blocked_by"])
+		}
+		ret, err = postJSON("api/task?id="+task, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{projectTask}, ret["blocked_by"])
+		_, err = requestJSON("api/task/dependencies?task_id="+task+"&blocked_by="+projectTask, nil, http.MethodDelete)
+		assert.NoError(t, err)
+
+		// Редактор проекта видит и меняет задачи проекта и может добавлять в него новые
+		code, _, ret = request(t, "api/shared", nil, http.MethodGet, asUser(editorToken))
+		assert.Equal(t, http.StatusOK, code)
+		projects, _ := ret["projects"].([]any)
+		assert.Len(t, projects, 1)
+
+		code, _, _ = request(t, "api/task", map[string]any{
+			"id": projectTask, "date": now, "title": "Купить батон", "comment": "", "repeat": "",
+		}, http.MethodPut, asUser(editorToken))
+		assert.Equal(t, http.StatusOK, code)
+		code, _, _ = request(t, "api/task?id="+task, nil, http.MethodGet, asUser(editorToken))
+		assert.Equal(t, http.StatusNotFound, code)
+
+		code, _, ret = request(t, "api/task", map[string]any{
+			"date": now, "title": "Купить молоко", "project_id": project,
+		}, http.MethodPost, asUser(editorToken))
+		assert.Equal(t, http.StatusOK, code)
+		added := fmt.Sprint(ret["id"])
+		assert.Contains(t, getTaskIDs(t, "project_id="+project), added)
+
+		code, _, _ = request(t, "api/task/done?id="+added, nil, http.MethodPost, asUser(editorToken))
+		assert.Equal(t, http.StatusOK, code)
+
+		// Управлять доступом может только владелец
+		code, _, _ = request(t, "api/share", map[string]any{"project_id": project, "login": viewer}, http.MethodPost, asUser(editorToken))
+		assert.Equal(t, http.StatusForbidden, code)
+	}
+
+	_, err = requestJSON("api/share?id="+projectShare, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	body, err = requestJSON("api/share?project_id="+project, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(body, &list))
+	assert.Empty(t, list["shares"])
+
+	_, err = requestJSON("api/task?id="+task, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	_, err = requestJSON("api/task?id="+projectTask, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	_, err = requestJSON("api/project?id="+project, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigninLockout(t *testing.T) {
+	signin := func(login, password string) *http.Response {
+		data, err := json.Marshal(map[string]any{"login": login, "password": password})
+		assert.NoError(t, err)
+		resp, err := http.Post(getURL("api/signin"), "application/json", bytes.NewBuffer(data))
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	// Попытки входа под несуществующим логином тоже ограничены
+	login := fmt.Sprintf("nobody%d", time.Now().UnixNano())
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusUnauthorized, signin(login, "wrong").StatusCode)
+	}
+	resp := signin(login, "wrong")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	assert.NoError(t, err)
+	assert.Greater(t, retryAfter, 0)
+
+	if len(Token) == 0 {
+		return
+	}
+
+	// Успешный вход сбрасывает счётчик неудач
+	password := "secret-password"
+	login = addUser(t, password)
+	assert.Equal(t, http.StatusUnauthorized, signin(login, "wrong").StatusCode)
+	assert.Equal(t, http.StatusOK, signin(login, password).StatusCode)
+
+	// Заблокированный логин не пускает даже с верным паролем
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusUnauthorized, signin(login, "wrong").StatusCode)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, signin(login, password).StatusCode)
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getTaskIDs(t *testing.T, query string) []string {
+	body, err := requestJSON("api/tasks?"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]any
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+
+	ids := make([]string, 0, len(m["tasks"]))
+	for _, task := range m["tasks"] {
+		ids = append(ids, fmt.Sprint(task["id"]))
+	}
+	return ids
+}
+
+func TestTags(t *testing.T) {
+	now := time.Now().Format(`20060102`)
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":  now,
+		"title": "Отчёт по проекту #Работа",
+		"tags":  []string{"срочно"},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	work := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"date":  now,
+		"title": "Позвонить маме",
+		"tags":  []string{"#Дом", "срочно"},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	home := fmt.Sprint(ret["id"])
+
+	task, err := postJSON("api/task?id="+work, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []any{"работа", "срочно"}, task["tags"])
+
+	assert.Subset(t, getTaskIDs(t, "tag=срочно"), []string{work, home})
+	ids := getTaskIDs(t, "tags=работа,дом")
+	assert.Subset(t, ids, []string{work, home})
+	ids = getTaskIDs(t, "tags=работа,срочно&tags_mode=all")
+	assert.Contains(t, ids, work)
+	assert.NotContains(t, ids, home)
+
+	// Без поля tags метки задачи сохраняются
+	ret, err = postJSON("api/task", map[string]any{
+		"id":    home,
+		"date":  now,
+		"title": "Позвонить маме вечером",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	task, err = postJSON("api/task?id="+home, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []any{"дом", "срочно"}, task["tags"])
+
+	body, err := requestJSON("api/tags", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list struct {
+		Tags []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"tags"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &list))
+	var homeTag string
+	for _, tag := range list.Tags {
+		if tag.Name == "дом" {
+			homeTag = tag.ID
+		}
+	}
+	assert.NotEmpty(t, homeTag)
+
+	ret, err = postJSON("api/tag", map[string]any{"name": "Срочно"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"], "Метка с существующим именем не должна создаваться")
+
+	ret, err = postJSON("api/tag?id="+homeTag, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NotContains(t, getTaskIDs(t, "tag=дом"), home)
+
+	for _, id := range []string{work, home} {
+		_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+}
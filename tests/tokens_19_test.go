@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPITokens(t *testing.T) {
+	bearer := func(token string) map[string]string {
+		return map[string]string{"Authorization": "Bearer " + token}
+	}
+
+	createToken := func(scopes ...string) (string, string) {
+		ret, err := postJSON("api/tokens", map[string]any{"name": "cron", "scopes": scopes}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		token := fmt.Sprint(ret["token"])
+		assert.True(t, strings.HasPrefix(token, "todo_pat_"))
+		return fmt.Sprint(ret["id"]), token
+	}
+
+	readID, readToken := createToken("read-only")
+	_, writeToken := createToken("tasks:write")
+	_, adminToken := createToken("admin")
+
+	ret, err := postJSON("api/tokens", map[string]any{"name": "cron", "scopes": []string{"everything"}}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	ret, err = postJSON("api/tokens", map[string]any{"name": "cron"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	ret, err = postJSON("api/tokens", map[string]any{"scopes": []string{"read-only"}}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	// В списке нет самих токенов, только их начало
+	body, err := requestJSON("api/tokens", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list struct {
+		Tokens []map[string]any `json:"tokens"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &list))
+	var found bool
+	for _, token := range list.Tokens {
+		assert.Nil(t, token["token"])
+		if token["id"] == readID {
+			found = true
+			assert.True(t, strings.HasPrefix(readToken, fmt.Sprint(token["prefix"])))
+			assert.Equal(t, []any{"read-only"}, token["scopes"])
+		}
+	}
+	assert.True(t, found)
+	assert.NotContains(t, string(body), readToken)
+
+	if len(Token) == 0 {
+		return
+	}
+
+	now := time.Now().Format(`20060102`)
+
+	code, _, _ := request(t, "api/tasks", nil, http.MethodGet, bearer(readToken))
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = request(t, "api/task", map[string]any{"date": now, "title": "Из скрипта"}, http.MethodPost, bearer(readToken))
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _, ret = request(t, "api/task", map[string]any{"date": now, "title": "Из скрипта"}, http.MethodPost, bearer(writeToken))
+	assert.Equal(t, http.StatusOK, code)
+	id := fmt.Sprint(ret["id"])
+	code, _, _ = request(t, "api/task?id="+id, nil, http.MethodGet, bearer(writeToken))
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = request(t, "api/task?id="+id, nil, http.MethodDelete, bearer(writeToken))
+	assert.Equal(t, http.StatusOK, code)
+
+	// Администрирование требует области admin
+	code, _, _ = request(t, "api/admin/users", nil, http.MethodGet, bearer(writeToken))
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = request(t, "api/admin/users", nil, http.MethodGet, bearer(adminToken))
+	assert.Equal(t, http.StatusOK, code)
+
+	// Токен не может выпускать другие токены
+	code, _, _ = request(t, "api/tokens", map[string]any{"name": "x", "scopes": []string{"admin"}}, http.MethodPost, bearer(adminToken))
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// Обычный токен доступа тоже принимается в заголовке
+	code, _, _ = request(t, "api/tasks", nil, http.MethodGet, bearer(Token))
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _, _ = request(t, "api/tasks", nil, http.MethodGet, bearer("todo_pat_bogus"))
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// Отозванный токен больше не действует
+	code, _, _ = request(t, "api/tokens?id="+readID, nil, http.MethodDelete, asUser(Token))
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = request(t, "api/tasks", nil, http.MethodGet, bearer(readToken))
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// Участник не может выпустить токен с областью admin
+	password := "secret-password"
+	login := addUser(t, password)
+	ret, err = postJSON("api/signin", map[string]any{"login": login, "password": password}, http.MethodPost)
+	assert.NoError(t, err)
+	member := fmt.Sprint(ret["token"])
+	code, _, _ = request(t, "api/tokens", map[string]any{"name": "x", "scopes": []string{"admin"}}, http.MethodPost, asUser(member))
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, ret = request(t, "api/tokens", map[string]any{"name": "x", "scopes": []string{"read-only"}}, http.MethodPost, asUser(member))
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = request(t, "api/tasks", nil, http.MethodGet, bearer(fmt.Sprint(ret["token"])))
+	assert.Equal(t, http.StatusOK, code)
+}
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Вычисляем код TOTP (RFC 6238) для интервала
+func totpCode(t *testing.T, secret string, step int64) string {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	assert.NoError(t, err)
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+// Подключаем двухфакторную аутентификацию и возвращаем секрет,
+// использованный интервал и коды восстановления
+func enableTOTP(t *testing.T, token string) (string, int64, []string) {
+	code, _, ret := request(t, "api/2fa/setup", nil, http.MethodPost, asUser(token))
+	assert.Equal(t, http.StatusOK, code)
+	secret := fmt.Sprint(ret["secret"])
+	assert.True(t, strings.HasPrefix(fmt.Sprint(ret["uri"]), "otpauth://totp/"))
+	assert.Contains(t, fmt.Sprint(ret["uri"]), "secret="+secret)
+
+	step := time.Now().Unix() / 30
+	code, _, ret = request(t, "api/2fa/enable", map[string]any{"code": totpCode(t, secret, step)}, http.MethodPost, asUser(token))
+	assert.Equal(t, http.StatusOK, code)
+	var recovery []string
+	codes, _ := ret["recovery_codes"].([]any)
+	for _, c := range codes {
+		recovery = append(recovery, fmt.Sprint(c))
+	}
+	assert.Len(t, recovery, 10)
+	return secret, step, recovery
+}
+
+func TestTwoFactor(t *testing.T) {
+	password := "secret-password"
+	signin := func(login string, values map[string]any) (int, map[string]any) {
+		values["login"] = login
+		values["password"] = password
+		code, _, ret := request(t, "api/signin", values, http.MethodPost, asUser(""))
+		return code, ret
+	}
+
+	// Без пароля приложения настройку проверяем на администраторе
+	var token, login string
+	if len(Token) > 0 {
+		login = addUser(t, password)
+		_, ret := signin(login, map[string]any{})
+		token = fmt.Sprint(ret["token"])
+	}
+
+	code, _, _ := request(t, "api/2fa/setup", nil, http.MethodPost, asUser(token))
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = request(t, "api/2fa/enable", map[string]any{"code": "abcdef"}, http.MethodPost, asUser(token))
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	secret, step, recovery := enableTOTP(t, token)
+
+	code, _, ret := request(t, "api/2fa", nil, http.MethodGet, asUser(token))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, ret["enabled"])
+	assert.Equal(t, float64(10), ret["recovery_codes_left"])
+
+	if len(Token) > 0 {
+		code, ret = signin(login, map[string]any{})
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.Equal(t, "two_factor_required", ret["code"])
+		assert.Empty(t, ret["token"])
+
+		// Код восстановления действует один раз
+		code, _ = signin(login, map[string]any{"recovery_code": strings.ToUpper(recovery[0])})
+		assert.Equal(t, http.StatusOK, code)
+		code, _ = signin(login, map[string]any{"recovery_code": recovery[0]})
+		assert.Equal(t, http.StatusUnauthorized, code)
+
+		// Уже принятый код TOTP повторно не принимается
+		code, _ = signin(login, map[string]any{"code": totpCode(t, secret, step)})
+		assert.Equal(t, http.StatusUnauthorized, code)
+		code, ret = signin(login, map[string]any{"code": totpCode(t, secret, step+1)})
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotEmpty(t, ret["token"])
+	}
+
+	code, _, _ = request(t, "api/2fa/disable", map[string]any{"code": "abcdef"}, http.MethodPost, asUser(token))
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = request(t, "api/2fa/disable", map[string]any{"recovery_code": recovery[1]}, http.MethodPost, asUser(token))
+	assert.Equal(t, http.StatusOK, code)
+	code, _, ret = request(t, "api/2fa", nil, http.MethodGet, asUser(token))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, ret["enabled"])
+}
+
+func TestRequireTwoFactor(t *testing.T) {
+	if len(Token) == 0 {
+		t.Skip("требуются TODO_PASSWORD и Token: без аутентификации двухфакторная проверка не выполняется")
+	}
+	password := "secret-password"
+
+	// Обязательную двухфакторную аутентификацию включает администратор, у которого она подключена
+	admin := fmt.Sprintf("admin%d", time.Now().UnixNano())
+	ret, err := postJSON("api/admin/users", map[string]any{"login": admin, "password": password, "role": "admin"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	ret, err = postJSON("api/signin", map[string]any{"login": admin, "password": password}, http.MethodPost)
+	assert.NoError(t, err)
+	adminToken := fmt.Sprint(ret["token"])
+	enableTOTP(t, adminToken)
+
+	member := addUser(t, password)
+	ret, err = postJSON("api/signin", map[string]any{"login": member, "password": password}, http.MethodPost)
+	assert.NoError(t, err)
+	memberToken := fmt.Sprint(ret["token"])
+
+	code, _, ret := request(t, "api/admin/settings", map[string]any{"require_2fa": true}, http.MethodPut, asUser(adminToken))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, ret["require_2fa"])
+	defer request(t, "api/admin/settings", map[string]any{"require_2fa": false}, http.MethodPut, asUser(adminToken))
+
+	// Пока второй фактор не подключён, доступна только его настройка
+	code, _, ret = request(t, "api/tasks", nil, http.MethodGet, asUser(memberToken))
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, "two_factor_setup_required", ret["code"])
+	code, _, ret = request(t, "api/2fa", nil, http.MethodGet, asUser(memberToken))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, ret["required"])
+
+	_, _, recovery := enableTOTP(t, memberToken)
+	code, _, _ = request(t, "api/tasks", nil, http.MethodGet, asUser(memberToken))
+	assert.Equal(t, http.StatusOK, code)
+
+	// Отключить обязательный второй фактор нельзя
+	code, _, _ = request(t, "api/2fa/disable", map[string]any{"recovery_code": recovery[0]}, http.MethodPost, asUser(memberToken))
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _, ret = request(t, "api/admin/settings", map[string]any{"require_2fa": false}, http.MethodPut, asUser(adminToken))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, ret["require_2fa"])
+}
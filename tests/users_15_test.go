@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsers(t *testing.T) {
+	login := fmt.Sprintf("user%d", time.Now().UnixNano())
+	password := "secret-password"
+
+	ret, err := postJSON("api/admin/users", map[string]any{
+		"login":    login,
+		"password": password,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.NotEmpty(t, ret["id"])
+
+	ret, err = postJSON("api/admin/users", map[string]any{
+		"login":    login,
+		"password": password,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"], "логин должен быть уникальным")
+
+	ret, err = postJSON("api/admin/users", map[string]any{
+		"login":    login + "x",
+		"password": "short",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"], "короткий пароль должен отклоняться")
+
+	body, err := requestJSON("api/admin/users", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &list))
+	found := false
+	for _, user := range list["users"] {
+		assert.Empty(t, user["password_hash"])
+		if user["login"] == login {
+			found = true
+			assert.Equal(t, "member", user["role"])
+		}
+	}
+	assert.True(t, found, "созданный пользователь должен быть в списке")
+
+	// Разделение задач между пользователями проверяется только при включённой аутентификации
+	if len(Token) == 0 {
+		return
+	}
+
+	ret, err = postJSON("api/signin", map[string]any{"login": login, "password": "wrong"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/signin", map[string]any{"login": login, "password": password}, http.MethodPost)
+	assert.NoError(t, err)
+	userToken := fmt.Sprint(ret["token"])
+	assert.NotEmpty(t, userToken)
+
+	now := time.Now().Format(`20060102`)
+	code, _, ret := request(t, "api/task", map[string]any{"date": now, "title": "Личная задача"}, http.MethodPost, asUser(userToken))
+	assert.Equal(t, http.StatusOK, code)
+	userTask := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task", map[string]any{"date": now, "title": "Задача администратора"}, http.MethodPost)
+	assert.NoError(t, err)
+	adminTask := fmt.Sprint(ret["id"])
+
+	code, _, _ = request(t, "api/task?id="+userTask, nil, http.MethodGet, asUser(userToken))
+	assert.Equal(t, http.StatusOK, code)
+
+	// Чужая задача для пользователя не существует
+	code, _, _ = request(t, "api/task?id="+adminTask, nil, http.MethodGet, asUser(userToken))
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = request(t, "api/task/done?id="+adminTask, nil, http.MethodPost, asUser(userToken))
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = request(t, "api/task?id="+adminTask, nil, http.MethodDelete, asUser(userToken))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	body, err = requestJSON("api/task?id="+userTask, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var task map[string]any
+	assert.NoError(t, json.Unmarshal(body, &task))
+	assert.NotEmpty(t, task["error"])
+
+	code, _, ret = request(t, "api/tasks", nil, http.MethodGet, asUser(userToken))
+	assert.Equal(t, http.StatusOK, code)
+	tasks, _ := ret["tasks"].([]any)
+	assert.Len(t, tasks, 1)
+
+	assert.NotContains(t, getTaskIDs(t, ""), userTask)
+
+	// Управлять пользователями может только администратор
+	code, _, _ = request(t, "api/admin/users", nil, http.MethodGet, asUser(userToken))
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _, _ = request(t, "api/task?id="+userTask, nil, http.MethodDelete, asUser(userToken))
+	assert.Equal(t, http.StatusOK, code)
+	_, err = requestJSON("api/task?id="+adminTask, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasksV2(t *testing.T) {
+	mergePatch := map[string]string{"Content-Type": "application/merge-patch+json"}
+
+	ret, err := postJSON("api/task", map[string]any{
+		"title":    "Подготовить отчёт",
+		"comment":  "Квартальный",
+		"priority": 2,
+		"tags":     []string{"работа"},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id, _ := ret["id"].(string)
+	assert.NotEmpty(t, id)
+	path := "api/v2/tasks/" + id
+
+	code, _, ret := request(t, path, nil, http.MethodGet, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Квартальный", ret["comment"])
+
+	// Непереданные поля сохраняют значения
+	code, _, ret = request(t, path, map[string]any{"title": "Подготовить годовой отчёт"}, http.MethodPatch, mergePatch)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Подготовить годовой отчёт", ret["title"])
+	assert.Equal(t, "Квартальный", ret["comment"])
+	assert.Equal(t, float64(2), ret["priority"])
+	assert.Equal(t, []any{"работа"}, ret["tags"])
+
+	// null очищает поле
+	code, _, ret = request(t, path, map[string]any{"comment": nil, "priority": nil, "tags": nil}, http.MethodPatch, mergePatch)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "", ret["comment"])
+	assert.Nil(t, ret["priority"])
+	assert.Nil(t, ret["tags"])
+	assert.Equal(t, "Подготовить годовой отчёт", ret["title"])
+
+	code, _, ret = request(t, path, map[string]any{"id": "1"}, http.MethodPatch, mergePatch)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, ret["fields"], "id")
+	code, _, ret = request(t, path, map[string]any{"title": nil}, http.MethodPatch, mergePatch)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, ret["fields"], "title")
+	code, _, _ = request(t, path, map[string]any{"priority": "high"}, http.MethodPatch, mergePatch)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, ret = request(t, path, map[string]any{"title": "x"}, http.MethodPatch, map[string]string{"Content-Type": "text/plain"})
+	assert.Equal(t, http.StatusUnsupportedMediaType, code)
+	assert.Equal(t, "unsupported_media_type", ret["code"])
+
+	// Повторяющаяся задача после выполнения переносится на следующую дату
+	code, _, _ = request(t, path, map[string]any{"repeat": "d 2"}, http.MethodPatch, mergePatch)
+	assert.Equal(t, http.StatusOK, code)
+	code, _, ret = request(t, path+"/complete", nil, http.MethodPost, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, time.Now().AddDate(0, 0, 2).Format("20060102"), ret["date"])
+
+	code, _, _ = request(t, path, map[string]any{"repeat": nil}, http.MethodPatch, mergePatch)
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = request(t, path+"/complete", nil, http.MethodPost, nil)
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _, ret = request(t, path, nil, http.MethodGet, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "not_found", ret["code"])
+
+	ret, err = postJSON("api/task", map[string]any{"title": "Удалить через v2"}, http.MethodPost)
+	assert.NoError(t, err)
+	path = "api/v2/tasks/" + ret["id"].(string)
+	code, _, _ = request(t, path, nil, http.MethodDelete, nil)
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _, _ = request(t, path, nil, http.MethodDelete, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Частичное изменение просроченной задачи не переносит её дату
+	db := openDB(t)
+	defer db.Close()
+	ret, err = postJSON("api/task", map[string]any{"title": "Просроченная задача", "repeat": "d 3"}, http.MethodPost)
+	assert.NoError(t, err)
+	id, _ = ret["id"].(string)
+	path = "api/v2/tasks/" + id
+	defer request(t, path, nil, http.MethodDelete, nil)
+	_, err = db.Exec(`UPDATE scheduler SET date = ? WHERE id = ?`, "20240101", id)
+	assert.NoError(t, err)
+
+	code, _, ret = request(t, path, map[string]any{"title": "Просроченная задача с новым заголовком"}, http.MethodPatch, mergePatch)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Просроченная задача с новым заголовком", ret["title"])
+	assert.Equal(t, "20240101", ret["date"])
+	var date string
+	assert.NoError(t, db.Get(&date, `SELECT date FROM scheduler WHERE id = ?`, id))
+	assert.Equal(t, "20240101", date)
+
+	// Изменение правила повторения переносит прошедшую дату, как и раньше
+	code, _, ret = request(t, path, map[string]any{"repeat": "d 1"}, http.MethodPatch, mergePatch)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, time.Now().AddDate(0, 0, 1).Format("20060102"), ret["date"])
+}
@@ -0,0 +1,23 @@
+package utils
+
+// Применяем JSON Merge Patch (RFC 7396) к документу, разобранному encoding/json:
+// поля патча заменяют поля документа, null удаляет поле, вложенные объекты
+// объединяются рекурсивно. Патч, не являющийся объектом, заменяет документ целиком
+func MergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = MergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Приводим текст к виду, пригодному для поиска без учёта регистра:
+// нижний регистр для любых букв Unicode и замена «ё» на «е»
+func FoldText(s string) string {
+	s = strings.ToLower(s)
+	return strings.ReplaceAll(s, "ё", "е")
+}
+
+// Разбиваем поисковую строку на термины: каждое слово нормализуется
+// и, если стемминг включён, обрезается до основы
+func SearchTerms(search string) []string {
+	words := strings.FieldsFunc(FoldText(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	stemming := os.Getenv("TODO_SEARCH_STEMMING") == "true"
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if stemming {
+			word = Stem(word)
+		}
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// Минимальная длина основы слова в символах, короче которой окончания не отрезаются
+const minStemLength = 3
+
+// Окончания русских слов, от самых длинных к самым коротким
+var russianEndings = []string{
+	"ившись", "ывшись", "вшись",
+	"ейшая", "ейшее", "ейший", "ейшие",
+	"ением", "ениям", "ениях",
+	"иями", "ями", "ами", "ение", "ения", "ений", "ению", "ении",
+	"ого", "его", "ому", "ему", "ыми", "ими", "ая", "яя", "ое", "ее", "ые", "ие",
+	"ый", "ий", "ой", "ую", "юю", "ых", "их", "ым", "им", "ом", "ем",
+	"ешь", "ишь", "ете", "ите", "ает", "яет", "ует", "ют", "ут", "ят", "ат",
+	"ить", "ать", "ять", "еть", "уть", "ыть", "ться", "тся", "ся", "сь",
+	"ила", "ило", "или", "ала", "ало", "али", "ыла", "ыло", "ыли", "ел", "ил", "ал", "ыл",
+	"ов", "ев", "ей", "ах", "ях", "ам", "ям", "ию", "ия", "ие", "ии", "ью", "ья",
+	"а", "я", "о", "е", "ы", "и", "у", "ю", "й", "ь",
+}
+
+// Окончания английских слов, от самых длинных к самым коротким
+var englishEndings = []string{
+	"ations", "ation", "ments", "ment", "ness", "ings", "ing", "ies", "ied",
+	"ers", "er", "ed", "es", "ly", "s",
+}
+
+// Отрезаем у слова типичное окончание, чтобы разные формы одного слова
+// совпадали при поиске. Язык определяем по алфавиту слова
+func Stem(word string) string {
+	endings := englishEndings
+	for _, r := range word {
+		if unicode.Is(unicode.Cyrillic, r) {
+			endings = russianEndings
+			break
+		}
+	}
+
+	runes := []rune(word)
+	for _, ending := range endings {
+		suffix := []rune(ending)
+		if len(runes)-len(suffix) < minStemLength {
+			continue
+		}
+		if strings.HasSuffix(word, ending) {
+			return string(runes[:len(runes)-len(suffix)])
+		}
+	}
+	return word
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Максимальная длина имени метки в символах
+const MaxTagLength = 64
+
+// Приводим имя метки к каноничному виду: без ведущей решётки,
+// пробелов по краям и в нижнем регистре
+func NormalizeTag(name string) (string, error) {
+	name = FoldText(strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(name), "#")))
+	if name == "" {
+		return "", errors.New("пустое имя метки")
+	}
+	if utf8.RuneCountInString(name) > MaxTagLength {
+		return "", errors.New("слишком длинное имя метки")
+	}
+	return name, nil
+}
+
+// Нормализуем список меток, отбрасывая повторы
+func NormalizeTags(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		tag, err := NormalizeTag(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// Находим в заголовке метки вида #метка
+func ExtractHashtags(title string) []string {
+	var tags []string
+	for _, word := range strings.Fields(title) {
+		if !strings.HasPrefix(word, "#") {
+			continue
+		}
+		tag := strings.TrimRightFunc(word[1:], func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if strings.IndexFunc(tag, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-'
+		}) >= 0 {
+			continue
+		}
+		if tag, err := NormalizeTag(tag); err == nil {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -2,11 +2,12 @@ package utils
 
 import (
 	"errors"
-	"fmt"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"todo-app/i18n"
 )
 
 // Вычисляем следующую дату задачи согласно правилам повторения
@@ -31,7 +32,7 @@ func NextDate(now time.Time, date string, repeat string) (string, error) {
 	case 'm':
 		return handleMonthly(now, start, repeat)
 	default:
-		return "", fmt.Errorf("указан неверный формат: %s", repeat)
+		return "", i18n.Errorf("указан неверный формат: %s", repeat)
 	}
 }
 
@@ -50,14 +51,14 @@ func handleYearly(now time.Time, start time.Time) (string, error) {
 func handleDaily(now, start time.Time, repeat string) (string, error) {
 	parts := strings.Split(repeat, " ")
 	if len(parts) != 2 {
-		return "", fmt.Errorf("указан неверный формат: %s", repeat)
+		return "", i18n.Errorf("указан неверный формат: %s", repeat)
 	}
 	days, err := strconv.Atoi(parts[1])
 	if err != nil {
-		return "", fmt.Errorf("указан неверный формат: %s", repeat)
+		return "", i18n.Errorf("указан неверный формат: %s", repeat)
 	}
 	if days < 1 || days > 400 {
-		return "", fmt.Errorf("d %d — превышен максимально допустимый интервал", days)
+		return "", i18n.Errorf("d %d — превышен максимально допустимый интервал", days)
 	}
 	next := start.AddDate(0, 0, days)
 	for !next.After(now) {
@@ -71,14 +72,14 @@ func handleWeekly(now, start time.Time, repeat string) (string, error) {
 	repeat = strings.TrimSpace(repeat[1:])
 	parts := strings.Split(repeat, ",")
 	if len(parts) == 0 {
-		return "", fmt.Errorf("указан неверный формат: %s", repeat)
+		return "", i18n.Errorf("указан неверный формат: %s", repeat)
 	}
 
 	daysOfWeek := []time.Weekday{}
 	for _, part := range parts {
 		day, err := strconv.Atoi(strings.TrimSpace(part))
 		if err != nil || day < 1 || day > 7 {
-			return "", fmt.Errorf("указан неверный формат: %s", repeat)
+			return "", i18n.Errorf("указан неверный формат: %s", repeat)
 		}
 		// Преобразуем число в соответствующий день недели (1 - понедельник, 7 - воскресенье)
 		daysOfWeek = append(daysOfWeek, time.Weekday(day%7)) // %7 чтобы 7 соответствовало воскресенью
@@ -103,7 +104,7 @@ func handleMonthly(now, start time.Time, repeat string) (string, error) {
 	repeat = strings.TrimSpace(repeat[1:])
 	parts := strings.Split(repeat, " ")
 	if len(parts) == 0 || len(parts) > 2 {
-		return "", fmt.Errorf("указан неверный формат: %s", repeat)
+		return "", i18n.Errorf("указан неверный формат: %s", repeat)
 	}
 
 	// Обрабатываем дни месяца
@@ -112,7 +113,7 @@ func handleMonthly(now, start time.Time, repeat string) (string, error) {
 	for _, day := range daysPart {
 		dayInt, err := strconv.Atoi(day)
 		if err != nil || dayInt < -2 || dayInt == 0 || dayInt > 31 {
-			return "", fmt.Errorf("указан неверный формат дня месяца: %s", day)
+			return "", i18n.Errorf("указан неверный формат дня месяца: %s", day)
 		}
 		daysMap[dayInt] = true
 	}
@@ -123,7 +124,7 @@ func handleMonthly(now, start time.Time, repeat string) (string, error) {
 		for _, m := range strings.Split(parts[1], ",") {
 			month, err := strconv.Atoi(m)
 			if err != nil || month < 1 || month > 12 {
-				return "", fmt.Errorf("указан неверный формат месяца: %s", parts[1])
+				return "", i18n.Errorf("указан неверный формат месяца: %s", parts[1])
 			}
 			monthsMap[month] = true
 		}